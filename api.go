@@ -21,16 +21,23 @@ var (
 )
 
 const (
-	listEventTypes      = "SportsAPING/v1.0/listEventTypes"
-	listCompetitions    = "SportsAPING/v1.0/listCompetitions"
-	listEvents          = "SportsAPING/v1.0/listEvents"
-	listCountries       = "SportsAPING/v1.0/listCountries"
-	listVenues          = "SportsAPING/v1.0/listVenues"
-	listMarketTypes     = "SportsAPING/v1.0/listMarketTypes"
-	listMarketCatalogue = "SportsAPING/v1.0/listMarketCatalogue"
-	listMarketBook      = "SportsAPING/v1.0/listMarketBook"
-	listCurrentOrders   = "SportsAPING/v1.0/listCurrentOrders"
-	listClearedOrders   = "SportsAPING/v1.0/listClearedOrders"
+	listEventTypes          = "SportsAPING/v1.0/listEventTypes"
+	listCompetitions        = "SportsAPING/v1.0/listCompetitions"
+	listEvents              = "SportsAPING/v1.0/listEvents"
+	listCountries           = "SportsAPING/v1.0/listCountries"
+	listVenues              = "SportsAPING/v1.0/listVenues"
+	listTimeRanges          = "SportsAPING/v1.0/listTimeRanges"
+	listMarketTypes         = "SportsAPING/v1.0/listMarketTypes"
+	listMarketCatalogue     = "SportsAPING/v1.0/listMarketCatalogue"
+	listMarketBook          = "SportsAPING/v1.0/listMarketBook"
+	listRunnerBook          = "SportsAPING/v1.0/listRunnerBook"
+	listCurrentOrders       = "SportsAPING/v1.0/listCurrentOrders"
+	listClearedOrders       = "SportsAPING/v1.0/listClearedOrders"
+	listMarketProfitAndLoss = "SportsAPING/v1.0/listMarketProfitAndLoss"
+	placeOrders             = "SportsAPING/v1.0/placeOrders"
+	cancelOrders            = "SportsAPING/v1.0/cancelOrders"
+	replaceOrders           = "SportsAPING/v1.0/replaceOrders"
+	updateOrders            = "SportsAPING/v1.0/updateOrders"
 )
 
 func (opts1 Options) Merge(opts2 Options) Options {
@@ -48,7 +55,8 @@ func (opts1 Options) Merge(opts2 Options) Options {
 }
 
 type API struct {
-	session *Session
+	session      *Session
+	transactions transactionCounter
 }
 
 func NewAPI(session *Session) *API {
@@ -56,27 +64,91 @@ func NewAPI(session *Session) *API {
 }
 
 func (api *API) ListEventTypes(options Options) (payload []EventTypeResult, err error) {
-	err = api.doRequest(listEventTypes, &payload, extendOptions(Options{"filter": MarketFilter{}}, options))
-	return payload, err
+	var request ListEventTypesRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{"filter": MarketFilter{}}, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListEventTypesWith(request)
+}
+
+func (api *API) ListEventTypesWith(request ListEventTypesRequest) (result []EventTypeResult, err error) {
+	err = api.doTypedRequest(listEventTypes, &result, request.Exchange, request)
+	return result, err
 }
 
 func (api *API) ListCompetitions(options Options) (result []CompetitionResult, err error) {
-	err = api.doRequest(listCompetitions, &result, extendOptions(Options{"filter": MarketFilter{}}, options))
+	var request ListCompetitionsRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{"filter": MarketFilter{}}, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListCompetitionsWith(request)
+}
+
+func (api *API) ListCompetitionsWith(request ListCompetitionsRequest) (result []CompetitionResult, err error) {
+	err = api.doTypedRequest(listCompetitions, &result, request.Exchange, request)
 	return result, err
 }
 
 func (api *API) ListEvents(options Options) (result []EventResult, err error) {
-	err = api.doRequest(listEvents, &result, extendOptions(Options{"filter": MarketFilter{}}, options))
+	var request ListEventsRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{"filter": MarketFilter{}}, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListEventsWith(request)
+}
+
+func (api *API) ListEventsWith(request ListEventsRequest) (result []EventResult, err error) {
+	err = api.doTypedRequest(listEvents, &result, request.Exchange, request)
 	return result, err
 }
 
 func (api *API) ListCountries(options Options) (result []CountryResult, err error) {
-	err = api.doRequest(listCountries, &result, extendOptions(Options{"filter": MarketFilter{}}, options))
+	var request ListCountriesRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{"filter": MarketFilter{}}, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListCountriesWith(request)
+}
+
+func (api *API) ListCountriesWith(request ListCountriesRequest) (result []CountryResult, err error) {
+	err = api.doTypedRequest(listCountries, &result, request.Exchange, request)
 	return result, err
 }
 
 func (api *API) ListVenues(options Options) (result []VenueResult, err error) {
-	err = api.doRequest(listVenues, &result, extendOptions(Options{"filter": MarketFilter{}}, options))
+	var request ListVenuesRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{"filter": MarketFilter{}}, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListVenuesWith(request)
+}
+
+func (api *API) ListVenuesWith(request ListVenuesRequest) (result []VenueResult, err error) {
+	err = api.doTypedRequest(listVenues, &result, request.Exchange, request)
+	return result, err
+}
+
+func (api *API) ListTimeRanges(filter MarketFilter, granularity TimeGranularity) (result []TimeRangeResult, err error) {
+	return api.ListTimeRangesWith(ListTimeRangesRequest{Filter: filter, Granularity: granularity})
+}
+
+func (api *API) ListTimeRangesWith(request ListTimeRangesRequest) (result []TimeRangeResult, err error) {
+	err = api.doTypedRequest(listTimeRanges, &result, request.Exchange, request)
 	return result, err
 }
 
@@ -87,7 +159,57 @@ func (api *API) ListMarketCatalogue(options Options) (result []MarketCatalogue,
 		"maxResults":       1000,
 	}
 
-	err = api.doRequest(listMarketCatalogue, &result, extendOptions(catalogueDefaultOptions, options))
+	var request ListMarketCatalogueRequest
+	request.Exchange, err = requestFromOptions(extendOptions(catalogueDefaultOptions, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListMarketCatalogueWith(request)
+}
+
+// ListMarketCatalogueWith returns at most MaxResults markets, 1000 when it
+// is not set. Market ids are split over concurrent requests of as many
+// markets as the projection weight allows. Split results are sorted again
+// by traded volume or start time, MINIMUM_AVAILABLE and MAXIMUM_AVAILABLE
+// only order the markets within each request.
+func (api *API) ListMarketCatalogueWith(request ListMarketCatalogueRequest) (result []MarketCatalogue, err error) {
+	maxResults := request.MaxResults
+
+	if maxResults <= 0 {
+		maxResults = 1000
+	}
+
+	perRequest := catalogueMarketsPerRequest(request.MarketProjection)
+	request.MaxResults = catalogueMaxResults(request)
+
+	marketIDs := request.Filter.MarketIDs
+	chunks := chunkBounds(len(marketIDs), perRequest)
+	results := make([][]MarketCatalogue, len(chunks))
+
+	err = runConcurrently(len(chunks), func(i int) error {
+		chunk := request
+
+		if len(marketIDs) > 0 {
+			chunk.Filter.MarketIDs = marketIDs[chunks[i][0]:chunks[i][1]]
+		}
+
+		return api.doTypedRequest(listMarketCatalogue, &results[i], chunk.Exchange, chunk)
+	})
+
+	for _, markets := range results {
+		result = append(result, markets...)
+	}
+
+	if len(chunks) > 1 {
+		sortMarketCatalogue(result, request.Sort)
+	}
+
+	if len(result) > maxResults {
+		result = result[:maxResults]
+	}
+
 	return result, err
 }
 
@@ -96,7 +218,18 @@ func (api *API) ListMarketTypes(options Options) (result []MarketTypeResult, err
 		"filter": MarketFilter{},
 	}
 
-	err = api.doRequest(listMarketTypes, &result, extendOptions(listMarketTypesOptions, options))
+	var request ListMarketTypesRequest
+	request.Exchange, err = requestFromOptions(extendOptions(listMarketTypesOptions, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListMarketTypesWith(request)
+}
+
+func (api *API) ListMarketTypesWith(request ListMarketTypesRequest) (result []MarketTypeResult, err error) {
+	err = api.doTypedRequest(listMarketTypes, &result, request.Exchange, request)
 	return result, err
 }
 
@@ -105,19 +238,109 @@ func (api *API) ListMarketBook(marketIds []string, options Options) (result []Ma
 		"marketIds": marketIds,
 	}
 
-	err = api.doRequest(listMarketBook, &result, extendOptions(marketBookDefaultOptions, options))
+	var request ListMarketBookRequest
+	request.Exchange, err = requestFromOptions(extendOptions(marketBookDefaultOptions, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListMarketBookWith(request)
+}
+
+// ListMarketBookWith splits the market ids over concurrent requests within
+// the data weight of the price projection and merges the books in order
+func (api *API) ListMarketBookWith(request ListMarketBookRequest) (result []MarketBook, err error) {
+	err = checkPriceData(request.PriceProjection)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := chunkBounds(len(request.MarketIDs), marketsPerRequest(marketBookWeight(request.PriceProjection)))
+	results := make([][]MarketBook, len(chunks))
+
+	err = runConcurrently(len(chunks), func(i int) error {
+		chunk := request
+		chunk.MarketIDs = request.MarketIDs[chunks[i][0]:chunks[i][1]]
+		return api.doTypedRequest(listMarketBook, &results[i], chunk.Exchange, chunk)
+	})
+
+	for _, books := range results {
+		result = append(result, books...)
+	}
+
+	return result, err
+}
+
+// ListRunnerBook accepts the ListMarketBook options and an optional handicap
+func (api *API) ListRunnerBook(marketID string, selectionID int64, options Options) (result []MarketBook, err error) {
+	var runnerBookDefaultOptions = Options{
+		"marketId":    marketID,
+		"selectionId": selectionID,
+	}
+
+	var request ListRunnerBookRequest
+	request.Exchange, err = requestFromOptions(extendOptions(runnerBookDefaultOptions, options), &request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ListRunnerBookWith(request)
+}
+
+func (api *API) ListRunnerBookWith(request ListRunnerBookRequest) (result []MarketBook, err error) {
+	err = api.doTypedRequest(listRunnerBook, &result, request.Exchange, request)
 	return result, err
 }
 
 func (api *API) ListCurrentOrders(options Options) (result CurrentOrderSummaryReport, err error) {
-	var currentOrdersOptions = Options{}
-	err = api.doRequest(listCurrentOrders, &result, extendOptions(currentOrdersOptions, options))
+	var request ListCurrentOrdersRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{}, options), &request)
+
+	if err != nil {
+		return result, err
+	}
+
+	return api.ListCurrentOrdersWith(request)
+}
+
+func (api *API) ListCurrentOrdersWith(request ListCurrentOrdersRequest) (result CurrentOrderSummaryReport, err error) {
+	err = api.doTypedRequest(listCurrentOrders, &result, request.Exchange, request)
+	return result, err
+}
+
+func (api *API) ListClearedOrders(betStatus BetStatus, options Options) (result ClearedOrderSummaryReport, err error) {
+	var request ListClearedOrdersRequest
+	request.Exchange, err = requestFromOptions(extendOptions(Options{}, options), &request)
+
+	if err != nil {
+		return result, err
+	}
+
+	request.BetStatus = betStatus
+
+	return api.ListClearedOrdersWith(request)
+}
+
+func (api *API) ListClearedOrdersWith(request ListClearedOrdersRequest) (result ClearedOrderSummaryReport, err error) {
+	err = api.doTypedRequest(listClearedOrders, &result, request.Exchange, request)
+	result.GroupBy = request.GroupBy
 	return result, err
 }
 
-func (api *API) ListClearedOrders(betStatus string, options Options) (result ClearedOrderSummaryReport, err error) {
-	var clearedOrdersOptions = Options{}
-	err = api.doRequest(listClearedOrders, &result, extendOptions(clearedOrdersOptions, options))
+func (api *API) ListMarketProfitAndLoss(marketIds []string, includeSettledBets, includeBspBets, netOfCommission bool) (result []MarketProfitAndLoss, err error) {
+	return api.ListMarketProfitAndLossWith(ListMarketProfitAndLossRequest{
+		MarketIDs:          marketIds,
+		IncludeSettledBets: includeSettledBets,
+		IncludeBspBets:     includeBspBets,
+		NetOfCommission:    netOfCommission,
+	})
+}
+
+func (api *API) ListMarketProfitAndLossWith(request ListMarketProfitAndLossRequest) (result []MarketProfitAndLoss, err error) {
+	err = api.doTypedRequest(listMarketProfitAndLoss, &result, request.Exchange, request)
 	return result, err
 }
 
@@ -136,20 +359,149 @@ func (api *API) FetchNavigation(options Options) (*Navigation, error) {
 	return navigation, err
 }
 
-func (api *API) PlaceOrders() {
+// PlaceOrders accepts customerRef, marketVersion, customerStrategyRef and async
+// options. Missing customerRef and customerOrderRef values are generated so
+// calls lost to network errors can be retried and reconciled safely. Prices
+// are checked against the priceLadder option when it is set.
+func (api *API) PlaceOrders(marketID string, instructions []PlaceInstruction, options Options) (result PlaceExecutionReport, err error) {
+	ladder, options, err := priceLadderOption(options)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = validatePlaceInstructions(instructions, api.session.account.Currency, ladder)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = api.transactions.reserve(len(instructions))
+
+	if err != nil {
+		return result, err
+	}
+
+	options, instructions = withCustomerRefs(options, instructions)
+
+	for i, bounds := range chunkBounds(len(instructions), MaxPlaceInstructions) {
+		var report PlaceExecutionReport
+		report, err = api.placeOrdersWithRetry(marketID, instructions[bounds[0]:bounds[1]], chunkOptions(options, i))
+
+		if err != nil {
+			return result, err
+		}
+
+		result.merge(report)
+	}
+
+	return result, nil
 }
 
-func (api *API) CancelOrders() {
+// CancelOrders cancels all bets on the account when marketID is blank and
+// all bets on the market when instructions are empty
+func (api *API) CancelOrders(marketID string, instructions []CancelInstruction, options Options) (result CancelExecutionReport, err error) {
+	err = validateCancelInstructions(marketID, instructions)
+
+	if err != nil {
+		return result, err
+	}
+
+	for i, bounds := range chunkBounds(len(instructions), MaxCancelInstructions) {
+		var report CancelExecutionReport
+		var cancelOrdersOptions = Options{}
+
+		if marketID != "" {
+			cancelOrdersOptions["marketId"] = marketID
+		}
+
+		if len(instructions) > 0 {
+			cancelOrdersOptions["instructions"] = instructions[bounds[0]:bounds[1]]
+		}
+
+		err = api.doRequest(cancelOrders, &report, extendOptions(cancelOrdersOptions, chunkOptions(options, i)))
+
+		if err != nil {
+			return result, err
+		}
+
+		api.transactions.add(countFailedCancelInstructions(report))
+
+		result.merge(report)
+	}
+
+	return result, nil
 }
 
-func (api *API) UpdateOrders() {
+func (api *API) UpdateOrders(marketID string, instructions []UpdateInstruction, options Options) (result UpdateExecutionReport, err error) {
+	err = validateUpdateInstructions(instructions)
+
+	if err != nil {
+		return result, err
+	}
+
+	for i, bounds := range chunkBounds(len(instructions), MaxUpdateInstructions) {
+		var report UpdateExecutionReport
+		var updateOrdersOptions = Options{
+			"marketId":     marketID,
+			"instructions": instructions[bounds[0]:bounds[1]],
+		}
+
+		err = api.doRequest(updateOrders, &report, extendOptions(updateOrdersOptions, chunkOptions(options, i)))
+
+		if err != nil {
+			return result, err
+		}
+
+		api.transactions.add(countFailedUpdateInstructions(report))
+
+		result.merge(report)
+	}
+
+	return result, nil
 }
 
-func (api *API) ReplaceOrders() {
+// ReplaceOrders checks new prices against the priceLadder option when it is set
+func (api *API) ReplaceOrders(marketID string, instructions []ReplaceInstruction, options Options) (result ReplaceExecutionReport, err error) {
+	ladder, options, err := priceLadderOption(options)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = validateReplaceInstructions(instructions, ladder)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = api.transactions.reserve(len(instructions))
+
+	if err != nil {
+		return result, err
+	}
+
+	for i, bounds := range chunkBounds(len(instructions), MaxReplaceInstructions) {
+		var report ReplaceExecutionReport
+		var replaceOrdersOptions = Options{
+			"marketId":     marketID,
+			"instructions": instructions[bounds[0]:bounds[1]],
+		}
+
+		err = api.doRequest(replaceOrders, &report, extendOptions(replaceOrdersOptions, chunkOptions(options, i)))
+
+		if err != nil {
+			return result, err
+		}
+
+		result.merge(report)
+	}
+
+	return result, nil
 }
 
-func (api *API) buildRequestBody(method string, options Options) ([]byte, error) {
-	return json.Marshal(apiRequest{JSONRPC: "2.0", Method: method, Params: options})
+func (api *API) buildRequestBody(method string, params interface{}) ([]byte, error) {
+	return json.Marshal(apiRequest{JSONRPC: "2.0", Method: method, Params: params})
 }
 
 func (api *API) doRequest(method string, payload interface{}, options Options) error {
@@ -159,7 +511,26 @@ func (api *API) doRequest(method string, payload interface{}, options Options) e
 		return err
 	}
 
-	body, err := api.buildRequestBody(method, options)
+	return api.doEndpointRequest(endpoint, method, payload, options)
+}
+
+// doTypedRequest sends a typed request to the given exchange, blank for the default one
+func (api *API) doTypedRequest(method string, payload interface{}, exchange string, params interface{}) error {
+	if exchange == "" {
+		exchange = fmt.Sprintf("%v", defaultOptions["exchange"])
+	}
+
+	endpoint, err := buildExchangeEndpoint(Options{"exchange": exchange})
+
+	if err != nil {
+		return err
+	}
+
+	return api.doEndpointRequest(endpoint, method, payload, params)
+}
+
+func (api *API) doEndpointRequest(endpoint, method string, payload interface{}, params interface{}) error {
+	body, err := api.buildRequestBody(method, params)
 
 	if err != nil {
 		return err
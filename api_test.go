@@ -1,6 +1,10 @@
 package betfair
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -9,6 +13,75 @@ func getTestAPI() *API {
 	return NewAPI(GetTestSession())
 }
 
+type mockCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// returned by mock handlers to drop the connection without a response
+var errMockDisconnect = errors.New("disconnect")
+
+// returned by mock handlers to send a response cut off mid body
+var errMockTruncate = errors.New("truncate")
+
+// returned by mock handlers to hold the response until the client gives up
+var errMockStall = errors.New("stall")
+
+func getMockAPI(t *testing.T, handler func(call mockCall) interface{}) *API {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call mockCall
+
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Error(err)
+			return
+		}
+
+		result := handler(call)
+
+		if result == errMockDisconnect {
+			conn, _, err := w.(http.Hijacker).Hijack()
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			conn.Close()
+			return
+		}
+
+		if result == errMockStall {
+			<-r.Context().Done()
+			return
+		}
+
+		if result == errMockTruncate {
+			w.Write([]byte(`{"jsonrpc":"2.0","result":{"status":"SUCC`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(apiResponse{JSONRPC: "2.0", Result: result})
+	}))
+
+	endpoint := BettingApiEndpoints["uk"]
+	BettingApiEndpoints["uk"] = server.URL
+
+	t.Cleanup(func() {
+		BettingApiEndpoints["uk"] = endpoint
+		server.Close()
+	})
+
+	session, err := NewSession(&Account{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.ssoid = "mock"
+
+	return NewAPI(session)
+}
+
 func TestEventTypes(t *testing.T) {
 	var api = getTestAPI()
 	eventTypes, err := api.ListEventTypes(Options{"filter": MarketFilter{EventTypeIDs: []string{"1"}}})
@@ -91,6 +164,25 @@ func TestVenues(t *testing.T) {
 	}
 }
 
+func TestTimeRanges(t *testing.T) {
+	var api = getTestAPI()
+
+	from := time.Now()
+	to := from.Add(time.Hour * 24)
+
+	timeRanges, err := api.ListTimeRanges(MarketFilter{MarketStartTime: &TimeRange{From: from, To: to}}, TimeGranularityHours)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(timeRanges) == 0 {
+		t.Error("Could not get any time range")
+		return
+	}
+}
+
 func TestMarketTypes(t *testing.T) {
 	var api = getTestAPI()
 
@@ -148,6 +240,41 @@ func TestMarketBook(t *testing.T) {
 	}
 }
 
+func TestRunnerBook(t *testing.T) {
+	var api = getTestAPI()
+
+	markets, err := api.ListMarketCatalogue(Options{"maxResults": 1, "marketProjection": []string{"RUNNER_DESCRIPTION"}})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(markets) == 0 {
+		t.Fatal("No markets returned")
+	}
+
+	var market = markets[0]
+
+	if len(market.Runners) == 0 {
+		t.Fatalf("Market %s has no runners", market.MarketID)
+	}
+
+	var runner = market.Runners[0]
+
+	books, err := api.ListRunnerBook(market.MarketID, runner.SelectionID, Options{"handicap": runner.Handicap})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(books) == 0 || len(books[0].Runners) != 1 || books[0].Runners[0].SelectionID != runner.SelectionID {
+		t.Error("Could not get runner book")
+		return
+	}
+}
+
 func TestListCurrentOrders(t *testing.T) {
 	var api = getTestAPI()
 
@@ -159,6 +286,28 @@ func TestListCurrentOrders(t *testing.T) {
 	}
 }
 
+func TestMarketProfitAndLoss(t *testing.T) {
+	var api = getTestAPI()
+
+	markets, err := api.ListMarketCatalogue(Options{"maxResults": 1})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(markets) == 0 {
+		t.Fatal("No markets returned")
+	}
+
+	_, err = api.ListMarketProfitAndLoss([]string{markets[0].MarketID}, true, true, true)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestFetchNavigation(t *testing.T) {
 	var api = getTestAPI()
 
@@ -170,6 +319,187 @@ func TestFetchNavigation(t *testing.T) {
 	}
 }
 
+func TestPlaceOrders(t *testing.T) {
+	var params struct {
+		MarketID      string             `json:"marketId"`
+		Instructions  []PlaceInstruction `json:"instructions"`
+		CustomerRef   string             `json:"customerRef"`
+		MarketVersion MarketVersion      `json:"marketVersion"`
+	}
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if call.Method != placeOrders {
+			t.Errorf("Unexpected method %s", call.Method)
+		}
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		return PlaceExecutionReport{
+			CustomerRef: params.CustomerRef,
+			Status:      "SUCCESS",
+			MarketID:    params.MarketID,
+			InstructionReports: []PlaceInstructionReport{
+				{Status: "SUCCESS", OrderStatus: "EXECUTABLE", Instruction: params.Instructions[0], BetID: "31242604945"},
+			},
+		}
+	})
+
+	instruction := PlaceInstruction{
+		OrderType:   OrderTypeLimit,
+		SelectionID: 47972,
+		Side:        SideBack,
+		LimitOrder:  &LimitOrder{Size: 2, Price: 3.5, PersistenceType: PersistenceTypeLapse},
+	}
+
+	report, err := api.PlaceOrders("1.114363660", []PlaceInstruction{instruction}, Options{"customerRef": "ref", "marketVersion": MarketVersion{Version: 7}})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if params.MarketVersion.Version != 7 || params.CustomerRef != "ref" {
+		t.Errorf("Options were not sent: %+v", params)
+	}
+
+	if report.Status != "SUCCESS" || len(report.InstructionReports) != 1 {
+		t.Errorf("Unexpected report %+v", report)
+		return
+	}
+
+	if report.InstructionReports[0].BetID != "31242604945" || report.InstructionReports[0].Instruction.LimitOrder.Price != 3.5 {
+		t.Errorf("Unexpected instruction report %+v", report.InstructionReports[0])
+	}
+}
+
+func TestCancelOrders(t *testing.T) {
+	var params map[string]json.RawMessage
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if call.Method != cancelOrders {
+			t.Errorf("Unexpected method %s", call.Method)
+		}
+
+		params = nil
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		return CancelExecutionReport{
+			Status: "SUCCESS",
+			InstructionReports: []CancelInstructionReport{
+				{Status: "SUCCESS", Instruction: CancelInstruction{BetID: "31242604945", SizeReduction: 1}, SizeCancelled: 1},
+			},
+		}
+	})
+
+	_, err := api.CancelOrders("", nil, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, ok := params["marketId"]; ok {
+		t.Error("Market id sent when cancelling all orders")
+	}
+
+	if _, ok := params["instructions"]; ok {
+		t.Error("Instructions sent when cancelling all orders")
+	}
+
+	report, err := api.CancelOrders("1.114363660", []CancelInstruction{{BetID: "31242604945", SizeReduction: 1}}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(params["marketId"]) != `"1.114363660"` {
+		t.Errorf("Unexpected market id %s", params["marketId"])
+	}
+
+	if len(report.InstructionReports) != 1 || report.InstructionReports[0].SizeCancelled != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestReplaceOrders(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if call.Method != replaceOrders {
+			t.Errorf("Unexpected method %s", call.Method)
+		}
+
+		return ReplaceExecutionReport{
+			Status:   "SUCCESS",
+			MarketID: "1.114363660",
+			InstructionReports: []ReplaceInstructionReport{
+				{
+					Status:                  "SUCCESS",
+					CancelInstructionReport: &CancelInstructionReport{Status: "SUCCESS", Instruction: CancelInstruction{BetID: "31242604945"}, SizeCancelled: 2},
+					PlaceInstructionReport:  &PlaceInstructionReport{Status: "SUCCESS", BetID: "31242604946"},
+				},
+				{
+					Status:                  "FAILURE",
+					ErrorCode:               "BET_TAKEN_OR_LAPSED",
+					CancelInstructionReport: &CancelInstructionReport{Status: "FAILURE", Instruction: CancelInstruction{BetID: "31242604947"}},
+				},
+			},
+		}
+	})
+
+	instructions := []ReplaceInstruction{{BetID: "31242604945", NewPrice: 3.5}, {BetID: "31242604947", NewPrice: 4}}
+	report, err := api.ReplaceOrders("1.114363660", instructions, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	betIDs := report.BetIDs()
+
+	if len(betIDs) != 1 || betIDs["31242604945"] != "31242604946" {
+		t.Errorf("Unexpected bet ids %v", betIDs)
+	}
+}
+
+func TestUpdateOrders(t *testing.T) {
+	var params struct {
+		Instructions []UpdateInstruction `json:"instructions"`
+	}
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if call.Method != updateOrders {
+			t.Errorf("Unexpected method %s", call.Method)
+		}
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		return UpdateExecutionReport{
+			Status:             "SUCCESS",
+			MarketID:           "1.114363660",
+			InstructionReports: []UpdateInstructionReport{{Status: "SUCCESS", Instruction: params.Instructions[0]}},
+		}
+	})
+
+	instructions := []UpdateInstruction{{BetID: "31242604945", NewPersistenceType: PersistenceTypePersist}}
+	report, err := api.UpdateOrders("1.114363660", instructions, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(report.InstructionReports) != 1 || report.InstructionReports[0].Instruction.NewPersistenceType != PersistenceTypePersist {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
 func BenchmarkEventTypes(t *testing.B) {
 	var api = getTestAPI()
 	eventTypes, err := api.ListEventTypes(Options{"filter": MarketFilter{}})
@@ -0,0 +1,158 @@
+package betfair
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Interval between ListCurrentOrders polls while waiting for pending orders
+var AsyncPollInterval = time.Second
+
+// PendingOrder is an instruction placed with async enabled. The exchange
+// reports it PENDING without a bet id, the order resolves once it shows up
+// in the current orders under its customerOrderRef.
+type PendingOrder struct {
+	MarketID         string
+	CustomerOrderRef string
+	Report           PlaceInstructionReport
+
+	api      *API
+	m        sync.Mutex
+	order    CurrentOrderSumary
+	resolved bool
+}
+
+// PlaceOrdersAsync places the instructions with async enabled and returns a
+// pending order per instruction, in instruction order
+func (api *API) PlaceOrdersAsync(marketID string, instructions []PlaceInstruction, options Options) ([]*PendingOrder, error) {
+	report, err := api.PlaceOrders(marketID, instructions, options.Merge(Options{"async": true}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var orders = make([]*PendingOrder, len(report.InstructionReports))
+
+	for i, instructionReport := range report.InstructionReports {
+		orders[i] = &PendingOrder{
+			MarketID:         marketID,
+			CustomerOrderRef: instructionReport.Instruction.CustomerOrderRef,
+			Report:           instructionReport,
+			api:              api,
+			// failed instructions never reach the order book
+			resolved: instructionReport.Status == "FAILURE",
+		}
+	}
+
+	return orders, nil
+}
+
+// Resolved reports whether the order was found in the current orders or
+// failed when placed
+func (order *PendingOrder) Resolved() bool {
+	order.m.Lock()
+	defer order.m.Unlock()
+
+	return order.resolved
+}
+
+// Order returns the current order once resolved
+func (order *PendingOrder) Order() (CurrentOrderSumary, bool) {
+	order.m.Lock()
+	defer order.m.Unlock()
+
+	return order.order, order.resolved && order.order.BetID != ""
+}
+
+// BetID returns the bet id once resolved, blank until then
+func (order *PendingOrder) BetID() string {
+	current, _ := order.Order()
+	return current.BetID
+}
+
+func (order *PendingOrder) resolve(current CurrentOrderSumary) {
+	order.m.Lock()
+	defer order.m.Unlock()
+
+	order.order = current
+	order.resolved = true
+}
+
+// Poll looks the order up once and reports whether it is resolved
+func (order *PendingOrder) Poll() (bool, error) {
+	err := order.api.ResolvePendingOrders([]*PendingOrder{order})
+	return order.Resolved(), err
+}
+
+// Wait polls every AsyncPollInterval until the order resolves or timeout
+// passes, failed instructions return an error
+func (order *PendingOrder) Wait(timeout time.Duration) (CurrentOrderSumary, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resolved, err := order.Poll()
+
+		if err != nil {
+			return CurrentOrderSumary{}, err
+		}
+
+		if resolved {
+			break
+		}
+
+		if time.Now().Add(AsyncPollInterval).After(deadline) {
+			return CurrentOrderSumary{}, fmt.Errorf("Timed out waiting for order `%s`", order.CustomerOrderRef)
+		}
+
+		time.Sleep(AsyncPollInterval)
+	}
+
+	current, ok := order.Order()
+
+	if !ok {
+		return current, fmt.Errorf("Order `%s` failed: %s", order.CustomerOrderRef, order.Report.ErrorCode)
+	}
+
+	return current, nil
+}
+
+// ResolvePendingOrders polls the current orders once per market for every
+// unresolved order
+func (api *API) ResolvePendingOrders(orders []*PendingOrder) error {
+	var pending = map[string]map[string]*PendingOrder{}
+
+	for _, order := range orders {
+		if order.Resolved() || order.CustomerOrderRef == "" {
+			continue
+		}
+
+		if pending[order.MarketID] == nil {
+			pending[order.MarketID] = map[string]*PendingOrder{}
+		}
+
+		pending[order.MarketID][order.CustomerOrderRef] = order
+	}
+
+	for marketID, marketOrders := range pending {
+		var customerOrderRefs []string
+
+		for customerOrderRef := range marketOrders {
+			customerOrderRefs = append(customerOrderRefs, customerOrderRef)
+		}
+
+		report, err := api.ListCurrentOrdersWith(ListCurrentOrdersRequest{MarketIDs: []string{marketID}, CustomerOrderRefs: customerOrderRefs})
+
+		if err != nil {
+			return err
+		}
+
+		for _, current := range report.CurrentOrders {
+			if order, ok := marketOrders[current.CustomerOrderRef]; ok {
+				order.resolve(current)
+			}
+		}
+	}
+
+	return nil
+}
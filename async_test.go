@@ -0,0 +1,82 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPlaceOrdersAsync(t *testing.T) {
+	var async bool
+	var polls int
+	var customerOrderRef string
+
+	pollInterval := AsyncPollInterval
+	AsyncPollInterval = time.Millisecond
+	defer func() { AsyncPollInterval = pollInterval }()
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		switch call.Method {
+		case placeOrders:
+			var params struct {
+				Async        bool               `json:"async"`
+				Instructions []PlaceInstruction `json:"instructions"`
+			}
+
+			if err := json.Unmarshal(call.Params, &params); err != nil {
+				t.Error(err)
+			}
+
+			async = params.Async
+			customerOrderRef = params.Instructions[0].CustomerOrderRef
+
+			return PlaceExecutionReport{
+				Status: "SUCCESS",
+				InstructionReports: []PlaceInstructionReport{
+					{Status: "SUCCESS", OrderStatus: "PENDING", Instruction: params.Instructions[0]},
+					{Status: "FAILURE", ErrorCode: "INVALID_RUNNER", Instruction: params.Instructions[1]},
+				},
+			}
+		case listCurrentOrders:
+			polls++
+
+			if polls < 3 {
+				return CurrentOrderSummaryReport{}
+			}
+
+			return CurrentOrderSummaryReport{
+				CurrentOrders: []CurrentOrderSumary{{BetID: "31242604945", Status: "EXECUTABLE", CustomerOrderRef: customerOrderRef}},
+			}
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+
+	orders, err := api.PlaceOrdersAsync("1.114363660", []PlaceInstruction{getTestLimitInstruction(2, 2), getTestLimitInstruction(2, 2)}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !async || len(orders) != 2 || orders[0].Resolved() || orders[0].BetID() != "" {
+		t.Errorf("Expected pending orders placed with async, got %+v", orders)
+		return
+	}
+
+	if _, err := orders[1].Wait(time.Second); err == nil || !orders[1].Resolved() {
+		t.Error("Failed instruction should resolve with an error")
+	}
+
+	current, err := orders[0].Wait(time.Second)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if current.BetID != "31242604945" || orders[0].BetID() != "31242604945" || polls != 3 {
+		t.Errorf("Unexpected order %+v after %d polls", current, polls)
+	}
+}
@@ -0,0 +1,259 @@
+package betfair
+
+import "time"
+
+// Shortest MarketStartTime window a scan splits before partitioning by event
+// type and then by competition, markets outside of any competition by event
+var MinScanWindow = time.Minute
+
+// MarketCatalogueScanTask is a partition of the scanned filter
+type MarketCatalogueScanTask struct {
+	Window        *TimeRange `json:"window,omitempty"`
+	EventTypeID   string     `json:"eventTypeId,omitempty"`
+	CompetitionID string     `json:"competitionId,omitempty"`
+	EventID       string     `json:"eventId,omitempty"`
+}
+
+// MarketCatalogueScanProgress can be saved and passed to
+// ResumeMarketCatalogueScan to carry on an interrupted scan
+type MarketCatalogueScanProgress struct {
+	Pending       []MarketCatalogueScanTask `json:"pending"`
+	Completed     int                       `json:"completed"`
+	Truncated     int                       `json:"truncated"`
+	SeenMarketIDs []string                  `json:"seenMarketIds"`
+}
+
+// MarketCatalogueIterator walks every market of a filter. Partitions that
+// return maxResults markets are split by MarketStartTime window, then by
+// event type and competition, and markets are deduplicated by MarketID.
+// Events outside of the competitions get partitions of their own.
+type MarketCatalogueIterator struct {
+	api       *API
+	request   ListMarketCatalogueRequest
+	pending   []MarketCatalogueScanTask
+	seen      map[string]bool
+	seenIDs   []string
+	completed int
+	truncated int
+	markets   []MarketCatalogue
+	err       error
+}
+
+// ScanMarketCatalogue returns an iterator over every market of the request
+// filter, the filter's MarketStartTime bounds the scanned windows
+func (api *API) ScanMarketCatalogue(request ListMarketCatalogueRequest) *MarketCatalogueIterator {
+	return api.ResumeMarketCatalogueScan(request, MarketCatalogueScanProgress{
+		Pending: []MarketCatalogueScanTask{{Window: request.Filter.MarketStartTime}},
+	})
+}
+
+func (api *API) ResumeMarketCatalogueScan(request ListMarketCatalogueRequest, progress MarketCatalogueScanProgress) *MarketCatalogueIterator {
+	iterator := &MarketCatalogueIterator{
+		api:       api,
+		request:   request,
+		pending:   append([]MarketCatalogueScanTask(nil), progress.Pending...),
+		seen:      map[string]bool{},
+		completed: progress.Completed,
+		truncated: progress.Truncated,
+	}
+
+	for _, marketID := range progress.SeenMarketIDs {
+		iterator.seen[marketID] = true
+		iterator.seenIDs = append(iterator.seenIDs, marketID)
+	}
+
+	return iterator
+}
+
+// Next fetches partitions until one yields unseen markets, false when the
+// scan is complete or failed
+func (iterator *MarketCatalogueIterator) Next() bool {
+	iterator.markets = nil
+
+	for iterator.err == nil && len(iterator.pending) > 0 {
+		task := iterator.pending[len(iterator.pending)-1]
+		markets, err := iterator.scan(task)
+
+		if err != nil {
+			iterator.err = err
+			return false
+		}
+
+		iterator.pending = iterator.pending[:len(iterator.pending)-1]
+		iterator.completed++
+
+		if len(markets) >= catalogueMaxResults(iterator.request) {
+			subtasks, err := iterator.split(task)
+
+			if err != nil {
+				iterator.pending = append(iterator.pending, task)
+				iterator.completed--
+				iterator.err = err
+				return false
+			}
+
+			if len(subtasks) == 0 {
+				iterator.truncated++
+			}
+
+			// pushed in reverse so earlier partitions are scanned first
+			for i := len(subtasks) - 1; i >= 0; i-- {
+				iterator.pending = append(iterator.pending, subtasks[i])
+			}
+		}
+
+		for _, market := range markets {
+			if !iterator.seen[market.MarketID] {
+				iterator.seen[market.MarketID] = true
+				iterator.seenIDs = append(iterator.seenIDs, market.MarketID)
+				iterator.markets = append(iterator.markets, market)
+			}
+		}
+
+		if len(iterator.markets) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Markets returns the markets found by the last call to Next
+func (iterator *MarketCatalogueIterator) Markets() []MarketCatalogue {
+	return iterator.markets
+}
+
+func (iterator *MarketCatalogueIterator) Err() error {
+	return iterator.err
+}
+
+// Progress returns the state of the scan, partitions that could not be split
+// any further while still full are counted as truncated
+func (iterator *MarketCatalogueIterator) Progress() MarketCatalogueScanProgress {
+	return MarketCatalogueScanProgress{
+		Pending:       append([]MarketCatalogueScanTask(nil), iterator.pending...),
+		Completed:     iterator.completed,
+		Truncated:     iterator.truncated,
+		SeenMarketIDs: append([]string(nil), iterator.seenIDs...),
+	}
+}
+
+func (iterator *MarketCatalogueIterator) filter(task MarketCatalogueScanTask) MarketFilter {
+	filter := iterator.request.Filter
+	filter.MarketStartTime = task.Window
+
+	if task.EventTypeID != "" {
+		filter.EventTypeIDs = []string{task.EventTypeID}
+	}
+
+	if task.CompetitionID != "" {
+		filter.CompetitionIDs = []string{task.CompetitionID}
+	}
+
+	if task.EventID != "" {
+		filter.EventIDs = []string{task.EventID}
+	}
+
+	return filter
+}
+
+func (iterator *MarketCatalogueIterator) scan(task MarketCatalogueScanTask) ([]MarketCatalogue, error) {
+	request := iterator.request
+	request.Filter = iterator.filter(task)
+	return iterator.api.ListMarketCatalogueWith(request)
+}
+
+func (iterator *MarketCatalogueIterator) split(task MarketCatalogueScanTask) ([]MarketCatalogueScanTask, error) {
+	var subtasks []MarketCatalogueScanTask
+	filter := iterator.filter(task)
+
+	switch {
+	case task.Window == nil:
+		timeRanges, err := iterator.api.ListTimeRangesWith(ListTimeRangesRequest{Filter: filter, Granularity: TimeGranularityDays, Exchange: iterator.request.Exchange})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, timeRange := range timeRanges {
+			window := timeRange.TimeRange
+			subtasks = append(subtasks, MarketCatalogueScanTask{Window: &window, EventTypeID: task.EventTypeID, CompetitionID: task.CompetitionID})
+		}
+	case task.Window.To.Sub(task.Window.From) > MinScanWindow:
+		middle := task.Window.From.Add(task.Window.To.Sub(task.Window.From) / 2)
+		subtasks = append(subtasks,
+			MarketCatalogueScanTask{Window: &TimeRange{From: task.Window.From, To: middle}, EventTypeID: task.EventTypeID, CompetitionID: task.CompetitionID},
+			MarketCatalogueScanTask{Window: &TimeRange{From: middle, To: task.Window.To}, EventTypeID: task.EventTypeID, CompetitionID: task.CompetitionID},
+		)
+	case task.EventTypeID == "":
+		eventTypes, err := iterator.api.ListEventTypesWith(ListEventTypesRequest{Filter: filter, Exchange: iterator.request.Exchange})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, eventType := range eventTypes {
+			subtasks = append(subtasks, MarketCatalogueScanTask{Window: task.Window, EventTypeID: eventType.EventType.ID})
+		}
+	case task.CompetitionID == "" && task.EventID == "":
+		competitions, err := iterator.api.ListCompetitionsWith(ListCompetitionsRequest{Filter: filter, Exchange: iterator.request.Exchange})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var competitionIDs []string
+
+		for _, competition := range competitions {
+			competitionIDs = append(competitionIDs, competition.Competition.ID)
+			subtasks = append(subtasks, MarketCatalogueScanTask{Window: task.Window, EventTypeID: task.EventTypeID, CompetitionID: competition.Competition.ID})
+		}
+
+		events, err := iterator.eventsWithoutCompetition(filter, competitionIDs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, eventID := range events {
+			subtasks = append(subtasks, MarketCatalogueScanTask{Window: task.Window, EventTypeID: task.EventTypeID, EventID: eventID})
+		}
+	}
+
+	return subtasks, nil
+}
+
+// eventsWithoutCompetition returns the events of filter that belong to none
+// of the competitions, their markets are not covered by competition partitions
+func (iterator *MarketCatalogueIterator) eventsWithoutCompetition(filter MarketFilter, competitionIDs []string) ([]string, error) {
+	events, err := iterator.api.ListEventsWith(ListEventsRequest{Filter: filter, Exchange: iterator.request.Exchange})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var covered = map[string]bool{}
+
+	if len(competitionIDs) > 0 {
+		filter.CompetitionIDs = competitionIDs
+		competitionEvents, err := iterator.api.ListEventsWith(ListEventsRequest{Filter: filter, Exchange: iterator.request.Exchange})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range competitionEvents {
+			covered[event.Event.ID] = true
+		}
+	}
+
+	var eventIDs []string
+
+	for _, event := range events {
+		if !covered[event.Event.ID] {
+			eventIDs = append(eventIDs, event.Event.ID)
+		}
+	}
+
+	return eventIDs, nil
+}
@@ -0,0 +1,183 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func getTestCatalogueAPI(t *testing.T, count int) *API {
+	base := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	return getMockAPI(t, func(call mockCall) interface{} {
+		switch call.Method {
+		case listTimeRanges:
+			return []TimeRangeResult{{TimeRange: TimeRange{From: base, To: base.Add(24 * time.Hour)}, MarketCount: int64(count)}}
+		case listMarketCatalogue:
+			var request ListMarketCatalogueRequest
+
+			if err := json.Unmarshal(call.Params, &request); err != nil {
+				t.Error(err)
+			}
+
+			var markets = []MarketCatalogue{}
+
+			for i := 0; i < count && len(markets) < request.MaxResults; i++ {
+				start := base.Add(time.Duration(i) * 10 * time.Minute)
+				window := request.Filter.MarketStartTime
+
+				if window == nil || (!start.Before(window.From) && !start.After(window.To)) {
+					markets = append(markets, MarketCatalogue{MarketID: fmt.Sprintf("1.%d", i)})
+				}
+			}
+
+			return markets
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+}
+
+func TestScanMarketCatalogue(t *testing.T) {
+	api := getTestCatalogueAPI(t, 25)
+	iterator := api.ScanMarketCatalogue(ListMarketCatalogueRequest{MaxResults: 10})
+
+	var marketIDs = map[string]bool{}
+
+	for iterator.Next() {
+		for _, market := range iterator.Markets() {
+			if marketIDs[market.MarketID] {
+				t.Errorf("Market %s returned twice", market.MarketID)
+			}
+
+			marketIDs[market.MarketID] = true
+		}
+	}
+
+	if err := iterator.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(marketIDs) != 25 {
+		t.Errorf("Expected 25 markets, got %d", len(marketIDs))
+	}
+
+	if progress := iterator.Progress(); len(progress.Pending) != 0 || progress.Truncated != 0 || len(progress.SeenMarketIDs) != 25 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+}
+
+func TestResumeMarketCatalogueScan(t *testing.T) {
+	api := getTestCatalogueAPI(t, 25)
+	iterator := api.ScanMarketCatalogue(ListMarketCatalogueRequest{MaxResults: 10})
+
+	if !iterator.Next() {
+		t.Error(iterator.Err())
+		return
+	}
+
+	found := len(iterator.Markets())
+	body, err := json.Marshal(iterator.Progress())
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var progress MarketCatalogueScanProgress
+
+	if err := json.Unmarshal(body, &progress); err != nil {
+		t.Error(err)
+		return
+	}
+
+	iterator = api.ResumeMarketCatalogueScan(ListMarketCatalogueRequest{MaxResults: 10}, progress)
+
+	for iterator.Next() {
+		found += len(iterator.Markets())
+	}
+
+	if err := iterator.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if found != 25 {
+		t.Errorf("Expected 25 markets across the resumed scan, got %d", found)
+	}
+}
+
+func TestScanMarketCatalogueWithoutCompetition(t *testing.T) {
+	base := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	competitions := map[string]string{"1.1": "10", "1.2": "10", "1.3": "10", "1.4": "10", "1.5": "", "1.6": "", "1.7": ""}
+	events := map[string]string{"1.1": "100", "1.2": "100", "1.3": "101", "1.4": "101", "1.5": "102", "1.6": "102", "1.7": "103"}
+
+	matches := func(filter MarketFilter, marketID string) bool {
+		if len(filter.CompetitionIDs) > 0 && filter.CompetitionIDs[0] != competitions[marketID] {
+			return false
+		}
+
+		return len(filter.EventIDs) == 0 || filter.EventIDs[0] == events[marketID]
+	}
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var request ListMarketCatalogueRequest
+
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		switch call.Method {
+		case listEventTypes:
+			return []EventTypeResult{{MarketCount: 7, EventType: EventType{ID: "1"}}}
+		case listCompetitions:
+			return []CompetitionResult{{MarketCount: 4, Competition: Competition{ID: "10"}}}
+		case listEvents:
+			var results []EventResult
+
+			for _, eventID := range []string{"100", "101", "102", "103"} {
+				if eventID < "102" || len(request.Filter.CompetitionIDs) == 0 {
+					results = append(results, EventResult{Event: Event{ID: eventID}})
+				}
+			}
+
+			return results
+		case listMarketCatalogue:
+			var markets = []MarketCatalogue{}
+
+			for i := 1; i <= 7 && len(markets) < request.MaxResults; i++ {
+				if marketID := fmt.Sprintf("1.%d", i); matches(request.Filter, marketID) {
+					markets = append(markets, MarketCatalogue{MarketID: marketID})
+				}
+			}
+
+			return markets
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+
+	iterator := api.ScanMarketCatalogue(ListMarketCatalogueRequest{
+		Filter:     MarketFilter{MarketStartTime: &TimeRange{From: base, To: base.Add(MinScanWindow)}},
+		MaxResults: 5,
+	})
+
+	var found int
+
+	for iterator.Next() {
+		found += len(iterator.Markets())
+	}
+
+	if err := iterator.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if progress := iterator.Progress(); found != 7 || progress.Truncated != 0 {
+		t.Errorf("Expected 7 markets and no truncation, got %d and %+v", found, progress)
+	}
+}
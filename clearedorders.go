@@ -0,0 +1,156 @@
+package betfair
+
+import "fmt"
+
+// Records fetched per page by ClearedOrdersIterator when the request does
+// not set RecordCount
+var ClearedOrdersPageSize = 1000
+
+// ClearedOrdersIterator pages through listClearedOrders with fromRecord and
+// recordCount until the exchange reports no more records
+type ClearedOrdersIterator struct {
+	pager  recordPager
+	report ClearedOrderSummaryReport
+}
+
+// IterateClearedOrders returns an iterator starting at the request's FromRecord
+func (api *API) IterateClearedOrders(request ListClearedOrdersRequest) *ClearedOrdersIterator {
+	iterator := &ClearedOrdersIterator{}
+	request.RecordCount = pageSize(request.RecordCount, ClearedOrdersPageSize)
+
+	iterator.pager = recordPager{fromRecord: request.FromRecord, fetch: func(fromRecord int) (int, bool, error) {
+		request.FromRecord = fromRecord
+		report, err := api.ListClearedOrdersWith(request)
+		iterator.report = report
+		return len(report.ClearedOrers), report.MoreAvailable, err
+	}}
+
+	return iterator
+}
+
+// Next fetches the next page, false when every record was read or a request failed
+func (iterator *ClearedOrdersIterator) Next() bool {
+	iterator.report = ClearedOrderSummaryReport{}
+	return iterator.pager.next()
+}
+
+// Orders returns the page fetched by the last call to Next
+func (iterator *ClearedOrdersIterator) Orders() []ClearedOrderSummary {
+	return iterator.report.ClearedOrers
+}
+
+// Report returns the page fetched by the last call to Next with the
+// request's GroupBy, so grouped rows can be read with its totals accessors
+func (iterator *ClearedOrdersIterator) Report() ClearedOrderSummaryReport {
+	return iterator.report
+}
+
+func (iterator *ClearedOrdersIterator) Err() error {
+	return iterator.pager.err
+}
+
+func (report ClearedOrderSummaryReport) checkGroupBy(groupBy GroupBy) error {
+	if report.GroupBy != groupBy {
+		return fmt.Errorf("Cleared orders are grouped by `%v`, not `%v`", report.GroupBy, groupBy)
+	}
+
+	return nil
+}
+
+func (summary ClearedOrderSummary) totals() ClearedOrderTotals {
+	return ClearedOrderTotals{
+		BetCount:        summary.BetCount,
+		Profit:          summary.Profit,
+		Commission:      summary.Commission,
+		SettledDate:     summary.SettledDate,
+		LastMatchedDate: summary.LastMatchedDate,
+		ItemDescription: summary.ItemDescription,
+	}
+}
+
+// EventTypeTotals returns the rows of a report grouped by EVENT_TYPE
+func (report ClearedOrderSummaryReport) EventTypeTotals() ([]ClearedEventTypeTotals, error) {
+	if err := report.checkGroupBy(GroupByEventType); err != nil {
+		return nil, err
+	}
+
+	var result = make([]ClearedEventTypeTotals, len(report.ClearedOrers))
+
+	for i, summary := range report.ClearedOrers {
+		result[i] = ClearedEventTypeTotals{EventTypeID: summary.EventTypeID, ClearedOrderTotals: summary.totals()}
+	}
+
+	return result, nil
+}
+
+// EventTotals returns the rows of a report grouped by EVENT
+func (report ClearedOrderSummaryReport) EventTotals() ([]ClearedEventTotals, error) {
+	if err := report.checkGroupBy(GroupByEvent); err != nil {
+		return nil, err
+	}
+
+	var result = make([]ClearedEventTotals, len(report.ClearedOrers))
+
+	for i, summary := range report.ClearedOrers {
+		result[i] = ClearedEventTotals{EventTypeID: summary.EventTypeID, EventID: summary.EventID, ClearedOrderTotals: summary.totals()}
+	}
+
+	return result, nil
+}
+
+// MarketTotals returns the rows of a report grouped by MARKET
+func (report ClearedOrderSummaryReport) MarketTotals() ([]ClearedMarketTotals, error) {
+	if err := report.checkGroupBy(GroupByMarket); err != nil {
+		return nil, err
+	}
+
+	var result = make([]ClearedMarketTotals, len(report.ClearedOrers))
+
+	for i, summary := range report.ClearedOrers {
+		result[i] = ClearedMarketTotals{
+			EventTypeID:        summary.EventTypeID,
+			EventID:            summary.EventID,
+			MarketID:           summary.MarketID,
+			ClearedOrderTotals: summary.totals(),
+		}
+	}
+
+	return result, nil
+}
+
+// SideTotals returns the rows of a report grouped by SIDE, PriceMatched is
+// the average price matched across the side's bets
+func (report ClearedOrderSummaryReport) SideTotals() ([]ClearedSideTotals, error) {
+	if err := report.checkGroupBy(GroupBySide); err != nil {
+		return nil, err
+	}
+
+	var result = make([]ClearedSideTotals, len(report.ClearedOrers))
+
+	for i, summary := range report.ClearedOrers {
+		result[i] = ClearedSideTotals{
+			EventTypeID:        summary.EventTypeID,
+			EventID:            summary.EventID,
+			MarketID:           summary.MarketID,
+			SelectionID:        summary.SelectionID,
+			Handicap:           summary.Handicap,
+			Side:               summary.Side,
+			PriceMatched:       summary.PriceMatched,
+			SizeSettled:        summary.SizeSettled,
+			ClearedOrderTotals: summary.totals(),
+		}
+	}
+
+	return result, nil
+}
+
+// Bets returns the rows of an ungrouped or BET grouped report
+func (report ClearedOrderSummaryReport) Bets() ([]ClearedOrderSummary, error) {
+	if report.GroupBy != "" {
+		if err := report.checkGroupBy(GroupByBet); err != nil {
+			return nil, err
+		}
+	}
+
+	return report.ClearedOrers, nil
+}
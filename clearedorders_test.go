@@ -0,0 +1,136 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestListClearedOrdersBetStatus(t *testing.T) {
+	var request ListClearedOrdersRequest
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		return json.RawMessage(`{"clearedOrders":[{"betId":"31242604945","eventId":"27712036","eventTypeId":"7","priceReduced":false,
+			"itemDescription":{"eventTypeDesc":"Horse Racing","marketDesc":"2m Hcap","runnerDesc":"Silver Bullet","numberOfWinners":1}}],"moreAvailable":false}`)
+	})
+
+	report, err := api.ListClearedOrders(BetStatusVoided, Options{"includeItemDescription": true})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if request.BetStatus != BetStatusVoided || !request.IncludeItemDescription {
+		t.Errorf("Unexpected request %+v", request)
+	}
+
+	if len(report.ClearedOrers) != 1 || report.ClearedOrers[0].ItemDescription.RunnerDesc != "Silver Bullet" || report.ClearedOrers[0].EventTypeID != "7" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestClearedOrdersIterator(t *testing.T) {
+	var requests []ListClearedOrdersRequest
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var request ListClearedOrdersRequest
+
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		requests = append(requests, request)
+
+		return ClearedOrderSummaryReport{
+			ClearedOrers:  []ClearedOrderSummary{{BetID: fmt.Sprint(request.FromRecord)}},
+			MoreAvailable: len(requests) == 1,
+		}
+	})
+
+	iterator := api.IterateClearedOrders(ListClearedOrdersRequest{BetStatus: BetStatusSettled})
+
+	var betIDs []string
+
+	for iterator.Next() {
+		for _, order := range iterator.Orders() {
+			betIDs = append(betIDs, order.BetID)
+		}
+	}
+
+	if err := iterator.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(betIDs) != 2 || betIDs[1] != "1" || len(requests) != 2 || requests[1].BetStatus != BetStatusSettled || requests[1].RecordCount != ClearedOrdersPageSize {
+		t.Errorf("Read %v over requests %+v", betIDs, requests)
+	}
+}
+
+func TestGroupedClearedOrders(t *testing.T) {
+	var request ListClearedOrdersRequest
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		return json.RawMessage(`{"clearedOrders":[
+			{"eventTypeId":"1","betCount":120,"profit":35.5,"commission":1.78,"itemDescription":{"eventTypeDesc":"Soccer"}},
+			{"eventTypeId":"7","betCount":3,"profit":-12,"commission":0}],"moreAvailable":false}`)
+	})
+
+	report, err := api.ListClearedOrders(BetStatusSettled, Options{"groupBy": "EVENT_TYPE", "includeItemDescription": true})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if request.GroupBy != GroupByEventType || report.GroupBy != GroupByEventType {
+		t.Errorf("Unexpected group by %v, %v", request.GroupBy, report.GroupBy)
+	}
+
+	totals, err := report.EventTypeTotals()
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(totals) != 2 || totals[0].EventTypeID != "1" || totals[0].BetCount != 120 || totals[0].Profit != 35.5 || totals[0].ItemDescription.EventTypeDesc != "Soccer" || totals[1].Profit != -12 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+
+	if _, err := report.MarketTotals(); err == nil {
+		t.Error("Expected an error reading EVENT_TYPE rows as MARKET totals")
+	}
+
+	if _, err := report.Bets(); err == nil {
+		t.Error("Expected an error reading EVENT_TYPE rows as bets")
+	}
+}
+
+func TestGroupedClearedOrdersIterator(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		return json.RawMessage(`{"clearedOrders":[{"eventTypeId":"1","eventId":"27712036","marketId":"1.114363660","betCount":4,"profit":8.5}],"moreAvailable":false}`)
+	})
+
+	iterator := api.IterateClearedOrders(ListClearedOrdersRequest{BetStatus: BetStatusSettled, GroupBy: GroupByMarket})
+
+	if !iterator.Next() {
+		t.Error(iterator.Err())
+		return
+	}
+
+	totals, err := iterator.Report().MarketTotals()
+
+	if err != nil || len(totals) != 1 || totals[0].MarketID != "1.114363660" || totals[0].BetCount != 4 {
+		t.Errorf("Unexpected totals %+v, %v", totals, err)
+	}
+}
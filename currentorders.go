@@ -0,0 +1,54 @@
+package betfair
+
+// Records fetched per page by CurrentOrdersIterator when the request does
+// not set RecordCount
+var CurrentOrdersPageSize = 1000
+
+// CurrentOrdersIterator pages through listCurrentOrders with fromRecord and
+// recordCount until the exchange reports no more records
+type CurrentOrdersIterator struct {
+	pager  recordPager
+	orders []CurrentOrderSumary
+}
+
+// IterateCurrentOrders returns an iterator starting at the request's FromRecord
+func (api *API) IterateCurrentOrders(request ListCurrentOrdersRequest) *CurrentOrdersIterator {
+	iterator := &CurrentOrdersIterator{}
+	request.RecordCount = pageSize(request.RecordCount, CurrentOrdersPageSize)
+
+	iterator.pager = recordPager{fromRecord: request.FromRecord, fetch: func(fromRecord int) (int, bool, error) {
+		request.FromRecord = fromRecord
+		report, err := api.ListCurrentOrdersWith(request)
+		iterator.orders = report.CurrentOrders
+		return len(report.CurrentOrders), report.MoreAvailable, err
+	}}
+
+	return iterator
+}
+
+// Next fetches the next page, false when every record was read or a request failed
+func (iterator *CurrentOrdersIterator) Next() bool {
+	iterator.orders = nil
+	return iterator.pager.next()
+}
+
+// Orders returns the page fetched by the last call to Next
+func (iterator *CurrentOrdersIterator) Orders() []CurrentOrderSumary {
+	return iterator.orders
+}
+
+func (iterator *CurrentOrdersIterator) Err() error {
+	return iterator.pager.err
+}
+
+// AllCurrentOrders reads every page of the request
+func (api *API) AllCurrentOrders(request ListCurrentOrdersRequest) ([]CurrentOrderSumary, error) {
+	var orders []CurrentOrderSumary
+	iterator := api.IterateCurrentOrders(request)
+
+	for iterator.Next() {
+		orders = append(orders, iterator.Orders()...)
+	}
+
+	return orders, iterator.Err()
+}
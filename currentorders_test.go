@@ -0,0 +1,53 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCurrentOrdersIterator(t *testing.T) {
+	var requests []ListCurrentOrdersRequest
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var request ListCurrentOrdersRequest
+
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		requests = append(requests, request)
+
+		return CurrentOrderSummaryReport{
+			CurrentOrders: []CurrentOrderSumary{{BetID: fmt.Sprint(request.FromRecord)}, {BetID: fmt.Sprint(request.FromRecord + 1)}},
+			MoreAvailable: len(requests) == 1,
+		}
+	})
+
+	from := time.Date(2016, 5, 1, 0, 0, 0, 0, time.UTC)
+	orders, err := api.AllCurrentOrders(ListCurrentOrdersRequest{
+		MarketIDs:       []string{"1.114363660"},
+		OrderProjection: OrderProjectionExecutable,
+		DateRange:       &TimeRange{From: from, To: from.Add(time.Hour)},
+		OrderBy:         OrderByPlaceTime,
+		SortDir:         SortDirLatestToEarliest,
+		FromRecord:      4,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(orders) != 4 || orders[3].BetID != "7" || len(requests) != 2 {
+		t.Errorf("Read %d orders in %d requests", len(orders), len(requests))
+		return
+	}
+
+	request := requests[1]
+
+	if request.FromRecord != 6 || request.RecordCount != CurrentOrdersPageSize || request.OrderBy != OrderByPlaceTime || request.SortDir != SortDirLatestToEarliest || !request.DateRange.From.Equal(from) {
+		t.Errorf("Unexpected request %+v", request)
+	}
+}
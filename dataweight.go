@@ -0,0 +1,201 @@
+package betfair
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Maximum data weight of a request, the number of markets times the weight
+// of the requested projections
+var MaxDataWeight = 200
+
+// Weight of listMarketBook price data
+var PriceDataWeights = map[string]int{
+	"SP_AVAILABLE":   3,
+	"SP_TRADED":      7,
+	"EX_BEST_OFFERS": 5,
+	"EX_ALL_OFFERS":  17,
+	"EX_TRADED":      17,
+}
+
+// Weight of listMarketCatalogue market projections, the others weigh nothing
+var MarketProjectionWeights = map[string]int{
+	"MARKET_DESCRIPTION": 1,
+	"RUNNER_METADATA":    1,
+}
+
+// Requests a split listing call keeps in flight at once
+var MaxConcurrentRequests = 10
+
+// Weight of listMarketBook without price data
+const emptyPriceProjectionWeight = 2
+
+// Depth of EX_BEST_OFFERS its weight applies to
+const defaultBestPricesDepth = 3
+
+func marketBookWeight(projection *PriceProjection) int {
+	if projection == nil || len(projection.PriceData) == 0 {
+		return emptyPriceProjectionWeight
+	}
+
+	var weight int
+	var offers, traded bool
+
+	for _, priceData := range projection.PriceData {
+		dataWeight := PriceDataWeights[priceData]
+
+		switch priceData {
+		case "EX_BEST_OFFERS":
+			offers = true
+
+			if overrides := projection.ExBestOffersOverrides; overrides != nil && overrides.BestPricesDepth > 0 {
+				dataWeight = int(math.Ceil(float64(dataWeight) * float64(overrides.BestPricesDepth) / defaultBestPricesDepth))
+			}
+		case "EX_ALL_OFFERS":
+			offers = true
+		case "EX_TRADED":
+			traded = true
+		}
+
+		weight += dataWeight
+	}
+
+	// offers and traded volume together weigh less than apart
+	if offers && traded {
+		weight -= 2
+	}
+
+	return weight
+}
+
+// checkPriceData rejects price data the weights do not know, it would
+// otherwise weigh nothing and leave the markets per request unbounded
+func checkPriceData(projection *PriceProjection) error {
+	if projection == nil {
+		return nil
+	}
+
+	for _, priceData := range projection.PriceData {
+		if _, ok := PriceDataWeights[priceData]; !ok {
+			return fmt.Errorf("Unknown price data `%s`", priceData)
+		}
+	}
+
+	return nil
+}
+
+func marketCatalogueWeight(projections []string) (weight int) {
+	for _, projection := range projections {
+		weight += MarketProjectionWeights[projection]
+	}
+
+	return weight
+}
+
+// marketsPerRequest returns how many markets fit a request of the given
+// weight, zero when the weight does not limit them
+func marketsPerRequest(weight int) int {
+	if weight <= 0 {
+		return 0
+	}
+
+	if weight > MaxDataWeight {
+		return 1
+	}
+
+	return MaxDataWeight / weight
+}
+
+// catalogueMarketsPerRequest returns the markets a catalogue request can
+// return, 1000 and lowered to what the projection weight allows
+func catalogueMarketsPerRequest(projections []string) int {
+	perRequest := marketsPerRequest(marketCatalogueWeight(projections))
+
+	if perRequest == 0 || perRequest > 1000 {
+		return 1000
+	}
+
+	return perRequest
+}
+
+// catalogueMaxResults returns the markets a catalogue request can return,
+// 1000 by default and lowered to what the projection weight allows
+func catalogueMaxResults(request ListMarketCatalogueRequest) int {
+	maxResults := request.MaxResults
+
+	if maxResults <= 0 {
+		maxResults = 1000
+	}
+
+	if perRequest := catalogueMarketsPerRequest(request.MarketProjection); maxResults > perRequest {
+		maxResults = perRequest
+	}
+
+	return maxResults
+}
+
+// sortMarketCatalogue merges the order of split catalogue requests for the
+// sorts the catalogue carries the data of, start times need the
+// MARKET_START_TIME projection
+func sortMarketCatalogue(markets []MarketCatalogue, order string) {
+	var less func(a, b MarketCatalogue) bool
+
+	switch order {
+	case "MINIMUM_TRADED":
+		less = func(a, b MarketCatalogue) bool { return a.TotalMatched < b.TotalMatched }
+	case "MAXIMUM_TRADED":
+		less = func(a, b MarketCatalogue) bool { return a.TotalMatched > b.TotalMatched }
+	case "FIRST_TO_START":
+		less = func(a, b MarketCatalogue) bool { return a.MarketStartTime.Before(b.MarketStartTime) }
+	case "LAST_TO_START":
+		less = func(a, b MarketCatalogue) bool { return a.MarketStartTime.After(b.MarketStartTime) }
+	default:
+		return
+	}
+
+	sort.SliceStable(markets, func(i, j int) bool { return less(markets[i], markets[j]) })
+}
+
+// runConcurrently calls fn for every index with at most
+// MaxConcurrentRequests calls at once and returns the error of the lowest
+// index
+func runConcurrently(count int, fn func(i int) error) error {
+	if count == 1 {
+		return fn(0)
+	}
+
+	var errs = make([]error, count)
+	var wg sync.WaitGroup
+	var slots = make(chan struct{}, maxConcurrentRequests())
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[i] = fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func maxConcurrentRequests() int {
+	if MaxConcurrentRequests < 1 {
+		return 1
+	}
+
+	return MaxConcurrentRequests
+}
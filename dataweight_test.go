@@ -0,0 +1,224 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMarketBookWeight(t *testing.T) {
+	var cases = []struct {
+		projection *PriceProjection
+		weight     int
+	}{
+		{nil, 2},
+		{&PriceProjection{PriceData: []string{"SP_AVAILABLE"}}, 3},
+		{&PriceProjection{PriceData: []string{"EX_BEST_OFFERS"}}, 5},
+		{&PriceProjection{PriceData: []string{"EX_BEST_OFFERS", "EX_TRADED"}}, 20},
+		{&PriceProjection{PriceData: []string{"EX_ALL_OFFERS", "EX_TRADED"}}, 32},
+		{&PriceProjection{PriceData: []string{"EX_BEST_OFFERS"}, ExBestOffersOverrides: &ExBestOffersOverrides{BestPricesDepth: 10}}, 17},
+	}
+
+	for _, c := range cases {
+		if weight := marketBookWeight(c.projection); weight != c.weight {
+			t.Errorf("Expected weight %d for %+v, got %d", c.weight, c.projection, weight)
+		}
+	}
+
+	if weight := marketCatalogueWeight([]string{"EVENT", "MARKET_DESCRIPTION", "RUNNER_METADATA"}); weight != 2 {
+		t.Errorf("Expected catalogue weight 2, got %d", weight)
+	}
+}
+
+func TestMarketBookChunking(t *testing.T) {
+	var m sync.Mutex
+	var sizes []int
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var request ListMarketBookRequest
+
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		m.Lock()
+		sizes = append(sizes, len(request.MarketIDs))
+		m.Unlock()
+
+		var books []MarketBook
+
+		for _, marketID := range request.MarketIDs {
+			books = append(books, MarketBook{MarketID: marketID})
+		}
+
+		return books
+	})
+
+	var marketIDs []string
+
+	for i := 0; i < 90; i++ {
+		marketIDs = append(marketIDs, fmt.Sprintf("1.%d", i))
+	}
+
+	books, err := api.ListMarketBookWith(ListMarketBookRequest{
+		MarketIDs:       marketIDs,
+		PriceProjection: &PriceProjection{PriceData: []string{"EX_BEST_OFFERS"}},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sizes) != 3 {
+		t.Errorf("Expected 3 requests of at most 40 markets, got %v", sizes)
+	}
+
+	if len(books) != 90 {
+		t.Errorf("Expected 90 books, got %d", len(books))
+		return
+	}
+
+	for i, book := range books {
+		if book.MarketID != marketIDs[i] {
+			t.Errorf("Books were not merged in order, %s at %d", book.MarketID, i)
+			return
+		}
+	}
+}
+
+func TestMarketCatalogueChunking(t *testing.T) {
+	var m sync.Mutex
+	var sizes, maxResults []int
+	var inFlight, maxInFlight int
+
+	concurrentRequests := MaxConcurrentRequests
+	MaxConcurrentRequests = 2
+	defer func() { MaxConcurrentRequests = concurrentRequests }()
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var request ListMarketCatalogueRequest
+
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		m.Lock()
+		sizes = append(sizes, len(request.Filter.MarketIDs))
+		maxResults = append(maxResults, request.MaxResults)
+		inFlight++
+
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+
+		m.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		m.Lock()
+		inFlight--
+		m.Unlock()
+
+		var markets []MarketCatalogue
+
+		for _, marketID := range request.Filter.MarketIDs {
+			if len(markets) < request.MaxResults {
+				var index int
+				fmt.Sscanf(marketID, "1.%d", &index)
+				markets = append(markets, MarketCatalogue{MarketID: marketID, TotalMatched: float64(index / 100)})
+			}
+		}
+
+		return markets
+	})
+
+	var marketIDs []string
+
+	for i := 0; i < 250; i++ {
+		marketIDs = append(marketIDs, fmt.Sprintf("1.%d", i))
+	}
+
+	markets, err := api.ListMarketCatalogueWith(ListMarketCatalogueRequest{
+		Filter:           MarketFilter{MarketIDs: marketIDs},
+		MarketProjection: []string{"MARKET_DESCRIPTION", "RUNNER_METADATA"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sizes) != 3 || sizes[0] != 100 || maxResults[0] != 100 || len(markets) != 250 {
+		t.Errorf("Expected 3 requests of 100 markets, got %v, %v and %d markets", sizes, maxResults, len(markets))
+	}
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests at once, got %d", maxInFlight)
+	}
+
+	sizes, maxResults = nil, nil
+
+	markets, err = api.ListMarketCatalogueWith(ListMarketCatalogueRequest{
+		Filter:           MarketFilter{MarketIDs: marketIDs},
+		MarketProjection: []string{"MARKET_DESCRIPTION", "RUNNER_METADATA"},
+		MaxResults:       5,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sizes) != 3 || sizes[0] != 100 || maxResults[0] != 5 {
+		t.Errorf("Market ids should be split by weight only, got %v with %v results", sizes, maxResults)
+	}
+
+	if len(markets) != 5 || markets[0].MarketID != "1.0" {
+		t.Errorf("Expected the first 5 markets, got %d", len(markets))
+	}
+
+	markets, err = api.ListMarketCatalogueWith(ListMarketCatalogueRequest{
+		Filter:           MarketFilter{MarketIDs: marketIDs},
+		MarketProjection: []string{"MARKET_DESCRIPTION", "RUNNER_METADATA"},
+		Sort:             "MAXIMUM_TRADED",
+		MaxResults:       2,
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(markets) != 2 || markets[0].MarketID != "1.200" || markets[1].MarketID != "1.201" {
+		t.Errorf("Expected the most traded markets of every request, got %+v", markets)
+	}
+}
+
+func TestMarketBookUnknownPriceData(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		t.Errorf("Unexpected request %s", call.Method)
+		return nil
+	})
+
+	_, err := api.ListMarketBookWith(ListMarketBookRequest{
+		MarketIDs:       []string{"1.1", "1.2"},
+		PriceProjection: &PriceProjection{PriceData: []string{"EX_BEST_OFFER"}},
+	})
+
+	if err == nil {
+		t.Error("Unknown price data should be rejected")
+	}
+
+	for _, size := range []int{0, -1} {
+		if bounds := chunkBounds(3, size); len(bounds) != 1 || bounds[0] != [2]int{0, 3} {
+			t.Errorf("Expected one chunk for size %d, got %v", size, bounds)
+		}
+	}
+
+	if bounds := chunkBounds(0, 0); len(bounds) != 1 || bounds[0] != [2]int{0, 0} {
+		t.Errorf("Expected one empty chunk, got %v", bounds)
+	}
+}
@@ -0,0 +1,639 @@
+package betfair
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Change types of a change message, blank for a plain delta
+const (
+	ChangeTypeSubImage   = "SUB_IMAGE"
+	ChangeTypeResubDelta = "RESUB_DELTA"
+	ChangeTypeHeartbeat  = "HEARTBEAT"
+)
+
+// StreamMarketFilter selects the markets of a market subscription
+type StreamMarketFilter struct {
+	MarketIDs         []string      `json:"marketIds,omitempty"`
+	BspMarket         *bool         `json:"bspMarket,omitempty"`
+	BettingTypes      []BettingType `json:"bettingTypes,omitempty"`
+	EventTypeIDs      []string      `json:"eventTypeIds,omitempty"`
+	EventIDs          []string      `json:"eventIds,omitempty"`
+	TurnInPlayEnabled *bool         `json:"turnInPlayEnabled,omitempty"`
+	MarketTypes       []string      `json:"marketTypes,omitempty"`
+	Venues            []string      `json:"venues,omitempty"`
+	CountryCodes      []string      `json:"countryCodes,omitempty"`
+	RaceTypes         []string      `json:"raceTypes,omitempty"`
+}
+
+// MarketDataFilter selects the data sent for subscribed markets. Fields are
+// EX_BEST_OFFERS_DISP, EX_BEST_OFFERS, EX_ALL_OFFERS, EX_TRADED,
+// EX_TRADED_VOL, EX_LTP, EX_MARKET_DEF, SP_TRADED and SP_PROJECTED.
+type MarketDataFilter struct {
+	Fields       []string `json:"fields,omitempty"`
+	LadderLevels int      `json:"ladderLevels,omitempty"`
+}
+
+// MarketSubscriptionRequest selects the markets and data of a subscription.
+// HeartbeatMs (500 to 5000) is the interval of heartbeats while nothing
+// changes and ConflateMs (0 to 120000) merges the changes within it.
+type MarketSubscriptionRequest struct {
+	MarketFilter     StreamMarketFilter
+	MarketDataFilter MarketDataFilter
+	HeartbeatMs      int64
+	ConflateMs       int64
+}
+
+type marketSubscriptionMessage struct {
+	Op               string             `json:"op"`
+	ID               int64              `json:"id"`
+	InitialClk       string             `json:"initialClk,omitempty"`
+	Clk              string             `json:"clk,omitempty"`
+	HeartbeatMs      int64              `json:"heartbeatMs,omitempty"`
+	ConflateMs       int64              `json:"conflateMs,omitempty"`
+	MarketFilter     StreamMarketFilter `json:"marketFilter"`
+	MarketDataFilter MarketDataFilter   `json:"marketDataFilter"`
+}
+
+type StreamRunnerDefinition struct {
+	ID               int64        `json:"id"`
+	Handicap         float64      `json:"hc"`
+	SortPriority     int64        `json:"sortPriority"`
+	Status           RunnerStatus `json:"status"`
+	AdjustmentFactor float64      `json:"adjustmentFactor"`
+	BSP              float64      `json:"bsp"`
+	RemovalDate      *time.Time   `json:"removalDate"`
+}
+
+// StreamMarketDefinition is sent in full whenever one of its fields changes
+type StreamMarketDefinition struct {
+	Venue                 string                   `json:"venue"`
+	RaceType              string                   `json:"raceType"`
+	SettledTime           *time.Time               `json:"settledTime"`
+	Timezone              string                   `json:"timezone"`
+	EachWayDivisor        float64                  `json:"eachWayDivisor"`
+	BspMarket             bool                     `json:"bspMarket"`
+	TurnInPlayEnabled     bool                     `json:"turnInPlayEnabled"`
+	PriceLadderDefinition *PriceLadderDescription  `json:"priceLadderDefinition"`
+	PersistenceEnabled    bool                     `json:"persistenceEnabled"`
+	MarketBaseRate        float64                  `json:"marketBaseRate"`
+	EventID               string                   `json:"eventId"`
+	EventTypeID           string                   `json:"eventTypeId"`
+	NumberOfWinners       int64                    `json:"numberOfWinners"`
+	BettingType           BettingType              `json:"bettingType"`
+	MarketType            string                   `json:"marketType"`
+	MarketTime            time.Time                `json:"marketTime"`
+	SuspendTime           *time.Time               `json:"suspendTime"`
+	BspReconciled         bool                     `json:"bspReconciled"`
+	Complete              bool                     `json:"complete"`
+	InPlay                bool                     `json:"inPlay"`
+	CrossMatching         bool                     `json:"crossMatching"`
+	RunnersVoidable       bool                     `json:"runnersVoidable"`
+	NumberOfActiveRunners int64                    `json:"numberOfActiveRunners"`
+	BetDelay              int64                    `json:"betDelay"`
+	Status                MarketStatus             `json:"status"`
+	Runners               []StreamRunnerDefinition `json:"runners"`
+	Regulators            []string                 `json:"regulators"`
+	CountryCode           string                   `json:"countryCode"`
+	DiscountAllowed       bool                     `json:"discountAllowed"`
+	OpenDate              *time.Time               `json:"openDate"`
+	Version               int64                    `json:"version"`
+}
+
+// ProjectedPrice is a near or far starting price of the stream, sent as a
+// number or as the strings "NaN" and "Infinity"
+type ProjectedPrice float64
+
+func (price *ProjectedPrice) UnmarshalJSON(data []byte) error {
+	var text = string(data)
+
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+
+	if err != nil {
+		return err
+	}
+
+	*price = ProjectedPrice(value)
+	return nil
+}
+
+// String formats the price the way StartingPrices holds it
+func (price ProjectedPrice) String() string {
+	value := float64(price)
+
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// RunnerChange carries the changed ladders of a runner. Level ladders hold
+// [level, price, size] and price ladders [price, size], a zero size removes
+// the level or price.
+type RunnerChange struct {
+	ID       int64           `json:"id"`
+	Handicap float64         `json:"hc"`
+	TV       *float64        `json:"tv"`
+	LTP      *float64        `json:"ltp"`
+	SPN      *ProjectedPrice `json:"spn"`
+	SPF      *ProjectedPrice `json:"spf"`
+	BATB     [][3]float64    `json:"batb"`
+	BATL     [][3]float64    `json:"batl"`
+	BDATB    [][3]float64    `json:"bdatb"`
+	BDATL    [][3]float64    `json:"bdatl"`
+	ATB      [][2]float64    `json:"atb"`
+	ATL      [][2]float64    `json:"atl"`
+	SPB      [][2]float64    `json:"spb"`
+	SPL      [][2]float64    `json:"spl"`
+	TRD      [][2]float64    `json:"trd"`
+}
+
+// MarketChange is a delta of a market, a full image when Img is set. Con
+// marks changes conflated because they were published faster than read.
+type MarketChange struct {
+	ID               string                  `json:"id"`
+	Img              bool                    `json:"img"`
+	Con              bool                    `json:"con"`
+	TV               *float64                `json:"tv"`
+	MarketDefinition *StreamMarketDefinition `json:"marketDefinition"`
+	RunnerChanges    []RunnerChange          `json:"rc"`
+}
+
+// MarketChangeMessage is an mcm message of the market subscription.
+// PublishTime is in milliseconds since the epoch, ReceivedAt is local.
+type MarketChangeMessage struct {
+	Op            string         `json:"op"`
+	ID            int64          `json:"id"`
+	ChangeType    string         `json:"ct"`
+	Clk           string         `json:"clk"`
+	InitialClk    string         `json:"initialClk"`
+	PublishTime   int64          `json:"pt"`
+	HeartbeatMs   int64          `json:"heartbeatMs"`
+	ConflateMs    int64          `json:"conflateMs"`
+	Status        int            `json:"status"`
+	SegmentType   string         `json:"segmentType"`
+	MarketChanges []MarketChange `json:"mc"`
+	ReceivedAt    time.Time      `json:"-"`
+}
+
+// Latency returns the delay between publishing and receiving the message
+func (message MarketChangeMessage) Latency() time.Duration {
+	return message.ReceivedAt.Sub(streamTime(message.PublishTime))
+}
+
+// Stale reports whether the exchange flagged its data as out of date
+func (message MarketChangeMessage) Stale() bool {
+	return message.Status == streamStatusStale
+}
+
+// Conflated reports whether any market change of the message is conflated
+func (message MarketChangeMessage) Conflated() bool {
+	for _, change := range message.MarketChanges {
+		if change.Con {
+			return true
+		}
+	}
+
+	return false
+}
+
+// levelLadder maps a level to its price and size
+type levelLadder map[int]PriceSize
+
+func (ladder levelLadder) apply(changes [][3]float64) {
+	for _, change := range changes {
+		if change[2] == 0 {
+			delete(ladder, int(change[0]))
+			continue
+		}
+
+		ladder[int(change[0])] = PriceSize{Price: change[1], Size: change[2]}
+	}
+}
+
+func (ladder levelLadder) priceSizes() []PriceSize {
+	var levels = make([]int, 0, len(ladder))
+
+	for level := range ladder {
+		levels = append(levels, level)
+	}
+
+	sort.Ints(levels)
+
+	var result = make([]PriceSize, len(levels))
+
+	for i, level := range levels {
+		result[i] = ladder[level]
+	}
+
+	return result
+}
+
+// pricePoints maps a price to its size
+type pricePoints map[float64]float64
+
+func (ladder pricePoints) apply(changes [][2]float64) {
+	for _, change := range changes {
+		if change[1] == 0 {
+			delete(ladder, change[0])
+			continue
+		}
+
+		ladder[change[0]] = change[1]
+	}
+}
+
+// priceSizes returns the ladder by price, highest first when descending
+func (ladder pricePoints) priceSizes(descending bool) []PriceSize {
+	var result = make([]PriceSize, 0, len(ladder))
+
+	for price, size := range ladder {
+		result = append(result, PriceSize{Price: price, Size: size})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if descending {
+			return result[i].Price > result[j].Price
+		}
+
+		return result[i].Price < result[j].Price
+	})
+
+	return result
+}
+
+type runnerKey struct {
+	selectionID int64
+	handicap    float64
+}
+
+type runnerCache struct {
+	ltp, tv                  float64
+	spn, spf                 string
+	batb, batl, bdatb, bdatl levelLadder
+	atb, atl, spb, spl, trd  pricePoints
+}
+
+func newRunnerCache() *runnerCache {
+	return &runnerCache{
+		batb: levelLadder{}, batl: levelLadder{}, bdatb: levelLadder{}, bdatl: levelLadder{},
+		atb: pricePoints{}, atl: pricePoints{}, spb: pricePoints{}, spl: pricePoints{}, trd: pricePoints{},
+	}
+}
+
+func (runner *runnerCache) apply(change RunnerChange) {
+	if change.TV != nil {
+		runner.tv = *change.TV
+	}
+
+	if change.LTP != nil {
+		runner.ltp = *change.LTP
+	}
+
+	if change.SPN != nil {
+		runner.spn = change.SPN.String()
+	}
+
+	if change.SPF != nil {
+		runner.spf = change.SPF.String()
+	}
+
+	runner.batb.apply(change.BATB)
+	runner.batl.apply(change.BATL)
+	runner.bdatb.apply(change.BDATB)
+	runner.bdatl.apply(change.BDATL)
+	runner.atb.apply(change.ATB)
+	runner.atl.apply(change.ATL)
+	runner.spb.apply(change.SPB)
+	runner.spl.apply(change.SPL)
+	runner.trd.apply(change.TRD)
+}
+
+// available prefers the virtual best offers, then the best offers and
+// then the full ladder, whichever the subscription sends
+func (runner *runnerCache) available() (back, lay []PriceSize) {
+	switch {
+	case len(runner.bdatb) > 0 || len(runner.bdatl) > 0:
+		return runner.bdatb.priceSizes(), runner.bdatl.priceSizes()
+	case len(runner.batb) > 0 || len(runner.batl) > 0:
+		return runner.batb.priceSizes(), runner.batl.priceSizes()
+	}
+
+	return runner.atb.priceSizes(true), runner.atl.priceSizes(false)
+}
+
+type marketCache struct {
+	definition *StreamMarketDefinition
+	conflated  bool
+	tv         float64
+	runners    map[runnerKey]*runnerCache
+	order      []runnerKey
+}
+
+func (market *marketCache) apply(change MarketChange) {
+	market.conflated = change.Con
+
+	if change.MarketDefinition != nil {
+		market.definition = change.MarketDefinition
+	}
+
+	if change.TV != nil {
+		market.tv = *change.TV
+	}
+
+	for _, runnerChange := range change.RunnerChanges {
+		market.runner(runnerKey{runnerChange.ID, runnerChange.Handicap}).apply(runnerChange)
+	}
+}
+
+func (market *marketCache) runner(key runnerKey) *runnerCache {
+	runner, ok := market.runners[key]
+
+	if !ok {
+		runner = newRunnerCache()
+		market.runners[key] = runner
+		market.order = append(market.order, key)
+	}
+
+	return runner
+}
+
+// runnerKeys lists the runners in definition order followed by runners the
+// definition does not name
+func (market *marketCache) runnerKeys() []runnerKey {
+	var keys []runnerKey
+	var listed = map[runnerKey]bool{}
+
+	if market.definition != nil {
+		for _, definition := range market.definition.Runners {
+			key := runnerKey{definition.ID, definition.Handicap}
+			listed[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range market.order {
+		if !listed[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (market *marketCache) book(marketID string) MarketBook {
+	var book = MarketBook{MarketID: marketID, TotalMatched: market.tv}
+	var definitions = map[runnerKey]StreamRunnerDefinition{}
+
+	if definition := market.definition; definition != nil {
+		book.Status = definition.Status
+		book.BetDelay = definition.BetDelay
+		book.BspReconciled = definition.BspReconciled
+		book.Complete = definition.Complete
+		book.Inplay = definition.InPlay
+		book.NumberOfWinners = definition.NumberOfWinners
+		book.NumberOfRunners = int64(len(definition.Runners))
+		book.NumberOfActiveRunners = definition.NumberOfActiveRunners
+		book.CrossMatching = definition.CrossMatching
+		book.RunnersVoidable = definition.RunnersVoidable
+		book.Version = definition.Version
+
+		for _, runner := range definition.Runners {
+			definitions[runnerKey{runner.ID, runner.Handicap}] = runner
+		}
+	}
+
+	for _, key := range market.runnerKeys() {
+		var runner = Runner{SelectionID: key.selectionID, Handicap: key.handicap}
+		var sp = StartingPrices{}
+
+		if definition, ok := definitions[key]; ok {
+			runner.Status = definition.Status
+			runner.AdjustmentFactor = definition.AdjustmentFactor
+			runner.RemovalDate = definition.RemovalDate
+			sp.ActualSP = definition.BSP
+		}
+
+		if cache, ok := market.runners[key]; ok {
+			back, lay := cache.available()
+			runner.LastPriceTraded = cache.ltp
+			runner.TotalMatched = cache.tv
+			runner.EX = &ExchangePrices{AvailableToBack: back, AvailableToLay: lay, TradedVolume: cache.trd.priceSizes(false)}
+			sp.NearPrice = cache.spn
+			sp.FarPrice = cache.spf
+			sp.BackStakeTaken = cache.spb.priceSizes(true)
+			sp.LayLiabilityTaken = cache.spl.priceSizes(false)
+		}
+
+		runner.SP = &sp
+		book.Runners = append(book.Runners, runner)
+	}
+
+	return book
+}
+
+// MarketCache holds the markets of a market subscription, rebuilt from the
+// images and deltas of the stream. Closed markets stay cached until Prune
+// removes them.
+type MarketCache struct {
+	m       sync.RWMutex
+	markets map[string]*marketCache
+	stale   bool
+}
+
+func newMarketCache() *MarketCache {
+	return &MarketCache{markets: map[string]*marketCache{}}
+}
+
+func (cache *MarketCache) apply(message MarketChangeMessage) {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	cache.stale = message.Stale()
+
+	for _, change := range message.MarketChanges {
+		market, ok := cache.markets[change.ID]
+
+		if !ok || change.Img {
+			market = &marketCache{runners: map[runnerKey]*runnerCache{}}
+			cache.markets[change.ID] = market
+		}
+
+		market.apply(change)
+	}
+}
+
+func (cache *MarketCache) reset() {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	cache.markets = map[string]*marketCache{}
+}
+
+// MarketBook returns a snapshot of a cached market
+func (cache *MarketCache) MarketBook(marketID string) (MarketBook, bool) {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+
+	if !ok {
+		return MarketBook{}, false
+	}
+
+	return market.book(marketID), true
+}
+
+// MarketBooks returns snapshots of every cached market ordered by MarketID
+func (cache *MarketCache) MarketBooks() []MarketBook {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	var marketIDs = make([]string, 0, len(cache.markets))
+
+	for marketID := range cache.markets {
+		marketIDs = append(marketIDs, marketID)
+	}
+
+	sort.Strings(marketIDs)
+
+	var books = make([]MarketBook, len(marketIDs))
+
+	for i, marketID := range marketIDs {
+		books[i] = cache.markets[marketID].book(marketID)
+	}
+
+	return books
+}
+
+// Conflated reports whether the last change of a market was conflated
+func (cache *MarketCache) Conflated(marketID string) bool {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+	return ok && market.conflated
+}
+
+// Stale reports whether the last message flagged the exchange's data as out
+// of date
+func (cache *MarketCache) Stale() bool {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	return cache.stale
+}
+
+// MarketDefinition returns the last definition received for a market
+func (cache *MarketCache) MarketDefinition(marketID string) (*StreamMarketDefinition, bool) {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+
+	if !ok || market.definition == nil {
+		return nil, false
+	}
+
+	return market.definition, true
+}
+
+// Prune drops closed markets from the cache and returns the number of
+// markets removed
+func (cache *MarketCache) Prune() int {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	var removed int
+
+	for marketID, market := range cache.markets {
+		if market.definition != nil && market.definition.Status == MarketStatusClosed {
+			delete(cache.markets, marketID)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+type marketSubscription struct {
+	streamSubscription
+	request MarketSubscriptionRequest
+	cache   *MarketCache
+}
+
+func (subscription *marketSubscription) message() marketSubscriptionMessage {
+	return marketSubscriptionMessage{
+		Op:               "marketSubscription",
+		ID:               subscription.id,
+		InitialClk:       subscription.initialClk,
+		Clk:              subscription.clk,
+		HeartbeatMs:      subscription.request.HeartbeatMs,
+		ConflateMs:       subscription.request.ConflateMs,
+		MarketFilter:     subscription.request.MarketFilter,
+		MarketDataFilter: subscription.request.MarketDataFilter,
+	}
+}
+
+// SubscribeMarkets replaces the market subscription of the stream and
+// returns the cache its changes are applied to
+func (stream *Stream) SubscribeMarkets(request MarketSubscriptionRequest) (*MarketCache, error) {
+	var subscription = &marketSubscription{streamSubscription: newStreamSubscription(stream.nextID(), request.HeartbeatMs), request: request, cache: newMarketCache()}
+
+	stream.m.Lock()
+	previous := stream.marketSubscription
+	stream.marketSubscription = subscription
+	stream.m.Unlock()
+
+	_, err := stream.request(subscription.id, subscription.message())
+
+	if err != nil {
+		stream.m.Lock()
+
+		if stream.marketSubscription == subscription {
+			stream.marketSubscription = previous
+		}
+
+		stream.m.Unlock()
+		return nil, err
+	}
+
+	return subscription.cache, nil
+}
+
+// handleMarketChange applies a message of the current subscription to its
+// cache and emits it, messages of replaced subscriptions are dropped
+func (stream *Stream) handleMarketChange(message MarketChangeMessage) {
+	stream.m.Lock()
+	subscription := stream.marketSubscription
+	stream.m.Unlock()
+
+	if subscription == nil || subscription.id != message.ID {
+		return
+	}
+
+	if subscription.fullImage(message.ChangeType, message.SegmentType) {
+		subscription.cache.reset()
+		stream.emitEvent(CacheResetEvent{Op: "mcm", SubscriptionID: subscription.id})
+	}
+
+	subscription.update(message.InitialClk, message.Clk, message.HeartbeatMs)
+	subscription.cache.apply(message)
+	stream.emit(message)
+}
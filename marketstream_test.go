@@ -0,0 +1,256 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStreamMarketCache(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+
+		var subscription marketSubscriptionMessage
+		server.read(&subscription)
+
+		if subscription.Op != "marketSubscription" || subscription.MarketFilter.MarketIDs[0] != "1.120684740" || subscription.MarketDataFilter.LadderLevels != 3 {
+			t.Errorf("Unexpected subscription %+v", subscription)
+		}
+
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS","connectionClosed":false}`)
+		server.write(`{"op":"mcm","id":2,"initialClk":"AAA","clk":"AAA","pt":1465903680000,"ct":"SUB_IMAGE","mc":[{"id":"1.120684740","img":true,"tv":150.5,
+			"marketDefinition":{"status":"OPEN","inPlay":false,"betDelay":0,"bettingType":"ODDS","marketTime":"2016-06-14T13:00:00.000Z","version":1,
+				"runners":[{"id":101,"hc":0,"sortPriority":1,"status":"ACTIVE"},{"id":102,"hc":0,"sortPriority":2,"status":"ACTIVE"}]},
+			"rc":[{"id":101,"ltp":2.5,"tv":100,"batb":[[0,2.5,10],[1,2.48,20]],"batl":[[0,2.52,15]],"trd":[[2.5,100]]},
+				{"id":102,"batb":[[0,1.5,5]],"batl":[[0,1.6,7]]}]}]}`)
+		server.write(`{"op":"mcm","id":2,"clk":"AAB","pt":1465903681000,"mc":[{"id":"1.120684740","tv":160.5,
+			"rc":[{"id":101,"ltp":2.52,"tv":110,"batb":[[0,2.5,0],[1,2.48,25]],"batl":[[0,2.54,5]],"trd":[[2.52,10]]}]}]}`)
+		server.write(`{"op":"mcm","id":2,"clk":"AAC","pt":1465903682000,"mc":[{"id":"1.120684740",
+			"marketDefinition":{"status":"SUSPENDED","inPlay":true,"betDelay":5,"bettingType":"ODDS","marketTime":"2016-06-14T13:00:00.000Z","version":2,
+				"runners":[{"id":101,"hc":0,"sortPriority":1,"status":"ACTIVE"},{"id":102,"hc":0,"sortPriority":2,"status":"REMOVED","adjustmentFactor":12.5}]}}]}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cache, err := stream.SubscribeMarkets(MarketSubscriptionRequest{
+		MarketFilter:     StreamMarketFilter{MarketIDs: []string{"1.120684740"}},
+		MarketDataFilter: MarketDataFilter{Fields: []string{"EX_BEST_OFFERS", "EX_TRADED", "EX_LTP", "EX_MARKET_DEF"}, LadderLevels: 3},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for changes := 0; changes < 3; {
+		message, ok := <-stream.Messages()
+
+		if !ok {
+			t.Errorf("Stream stopped: %v", stream.Err())
+			return
+		}
+
+		if _, ok := message.(MarketChangeMessage); ok {
+			changes++
+		}
+	}
+
+	book, ok := cache.MarketBook("1.120684740")
+
+	if !ok {
+		t.Error("Market not cached")
+		return
+	}
+
+	if book.Status != MarketStatusSuspended || !book.Inplay || book.BetDelay != 5 || book.Version != 2 || book.TotalMatched != 160.5 || len(book.Runners) != 2 {
+		t.Errorf("Unexpected book %+v", book)
+		return
+	}
+
+	runner := book.Runners[0]
+	back := runner.EX.AvailableToBack
+
+	if runner.LastPriceTraded != 2.52 || runner.TotalMatched != 110 || len(back) != 1 || back[0] != (PriceSize{2.48, 25}) || runner.EX.AvailableToLay[0] != (PriceSize{2.54, 5}) {
+		t.Errorf("Unexpected runner %+v, %+v", runner, runner.EX)
+	}
+
+	if traded := runner.EX.TradedVolume; len(traded) != 2 || traded[0] != (PriceSize{2.5, 100}) || traded[1] != (PriceSize{2.52, 10}) {
+		t.Errorf("Unexpected traded volume %+v", traded)
+	}
+
+	if removed := book.Runners[1]; removed.Status != RunnerStatusRemoved || removed.AdjustmentFactor != 12.5 || removed.EX.AvailableToLay[0].Price != 1.6 {
+		t.Errorf("Unexpected runner %+v", removed)
+	}
+}
+
+func TestStreamHeartbeatConflationAndLatency(t *testing.T) {
+	var connections int
+	published := time.Now().Add(-50*time.Millisecond).UnixNano() / int64(time.Millisecond)
+
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		connections++
+		server.accept()
+
+		var subscription marketSubscriptionMessage
+		server.read(&subscription)
+
+		if subscription.HeartbeatMs != 500 || subscription.ConflateMs != 100 {
+			t.Errorf("Unexpected subscription %+v", subscription)
+		}
+
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+
+		// the first connection goes silent after its image
+		if connections == 1 {
+			server.write(fmt.Sprintf(`{"op":"mcm","id":2,"initialClk":"I1","clk":"C1","ct":"SUB_IMAGE","pt":%d,"heartbeatMs":10,"conflateMs":100,
+				"mc":[{"id":"1.1","img":true,"con":true,"rc":[{"id":101,"batb":[[0,2.5,10]]}]}]}`, published))
+			return
+		}
+
+		server.write(`{"op":"mcm","id":2,"clk":"C2","ct":"RESUB_DELTA","status":503,"mc":[{"id":"1.1","rc":[{"id":101,"batb":[[0,2.52,8]]}]}]}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cache, err := stream.SubscribeMarkets(MarketSubscriptionRequest{MarketFilter: StreamMarketFilter{MarketIDs: []string{"1.1"}}, HeartbeatMs: 500, ConflateMs: 100})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var changes int
+	var reconnect ReconnectEvent
+
+	for changes < 2 {
+		message, ok := <-stream.Messages()
+
+		if !ok {
+			t.Errorf("Stream stopped: %v", stream.Err())
+			return
+		}
+
+		switch message := message.(type) {
+		case ReconnectEvent:
+			reconnect = message
+		case MarketChangeMessage:
+			changes++
+
+			if changes == 1 && (!message.Conflated() || !cache.Conflated("1.1") || message.Latency() < 50*time.Millisecond || cache.Stale()) {
+				t.Errorf("Unexpected image %+v latency %v", message, message.Latency())
+			}
+		}
+	}
+
+	if reconnect.Cause != ErrMissedHeartbeat {
+		t.Errorf("Expected a reconnect after missed heartbeats, got %+v", reconnect)
+	}
+
+	if !cache.Stale() || cache.Conflated("1.1") {
+		t.Errorf("Expected stale, unconflated data")
+	}
+
+	if latency := stream.Latency(); latency.Count != 1 || latency.Max < 50*time.Millisecond {
+		t.Errorf("Unexpected latency %+v", latency)
+	}
+}
+
+func TestStreamUnknownEnumValues(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+
+		var subscription marketSubscriptionMessage
+		server.read(&subscription)
+
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+		server.write(`{"op":"mcm","id":2,"initialClk":"AAA","clk":"AAA","ct":"SUB_IMAGE","mc":[{"id":"1.1","img":true,
+			"marketDefinition":{"status":"NEW_MARKET_STATUS","bettingType":"NEW_BETTING_TYPE","marketTime":"2016-06-14T13:00:00.000Z","version":1,
+				"runners":[{"id":101,"sortPriority":1,"status":"NEW_STATUS"},{"id":102,"sortPriority":2,"status":"ACTIVE"}]}}]}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cache, err := stream.SubscribeMarkets(MarketSubscriptionRequest{MarketFilter: StreamMarketFilter{MarketIDs: []string{"1.1"}}})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for {
+		message, ok := <-stream.Messages()
+
+		if !ok {
+			t.Errorf("Stream stopped: %v", stream.Err())
+			return
+		}
+
+		if _, ok := message.(MarketChangeMessage); ok {
+			break
+		}
+	}
+
+	book, ok := cache.MarketBook("1.1")
+
+	if !ok || book.Status != "NEW_MARKET_STATUS" || len(book.Runners) != 2 || book.Runners[0].Status != "NEW_STATUS" || book.Runners[1].Status != RunnerStatusActive {
+		t.Errorf("Unexpected book %+v", book)
+	}
+}
+
+func TestMarketCacheProjectedPricesAndPrune(t *testing.T) {
+	var message MarketChangeMessage
+
+	err := json.Unmarshal([]byte(`{"op":"mcm","clk":"AAA","mc":[
+		{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","version":1},
+			"rc":[{"id":101,"spn":"NaN","spf":"Infinity"},{"id":102,"spn":3.45,"spf":2.5}]},
+		{"id":"1.2","img":true,"marketDefinition":{"status":"CLOSED","version":2},"rc":[{"id":101,"spn":null}]}]}`), &message)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newMarketCache()
+	cache.apply(message)
+
+	book, ok := cache.MarketBook("1.1")
+
+	if !ok || len(book.Runners) != 2 {
+		t.Fatalf("Unexpected book %+v", book)
+	}
+
+	if sp := book.Runners[0].SP; sp.NearPrice != "NaN" || sp.FarPrice != "Infinity" {
+		t.Errorf("Unexpected starting prices %+v", sp)
+	}
+
+	if sp := book.Runners[1].SP; sp.NearPrice != "3.45" || sp.FarPrice != "2.5" {
+		t.Errorf("Unexpected starting prices %+v", sp)
+	}
+
+	if book, ok := cache.MarketBook("1.2"); !ok || book.Runners[0].SP.NearPrice != "" {
+		t.Errorf("Expected no near price for a null spn, got %+v", book)
+	}
+
+	if removed := cache.Prune(); removed != 1 {
+		t.Errorf("Expected the closed market to be pruned, removed %d", removed)
+	}
+
+	if _, ok := cache.MarketBook("1.2"); ok || len(cache.MarketBooks()) != 1 {
+		t.Error("Expected only the open market to stay cached")
+	}
+}
@@ -0,0 +1,484 @@
+package betfair
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Maximum of instructions per placeOrders call
+var MaxPlaceInstructions = 200
+
+// Maximum of instructions per cancelOrders call
+var MaxCancelInstructions = 60
+
+// Maximum of instructions per replaceOrders call
+var MaxReplaceInstructions = 60
+
+// Maximum of instructions per updateOrders call
+var MaxUpdateInstructions = 60
+
+// Currency used for stake checks when the account does not set one
+var DefaultCurrency = "GBP"
+
+// Minimum stake per account currency, unknown currencies are not checked
+var MinimumStakes = map[string]float64{
+	"GBP": 1,
+	"EUR": 1,
+	"USD": 1,
+	"AUD": 1,
+	"CAD": 1,
+	"HKD": 10,
+	"DKK": 10,
+	"NOK": 10,
+	"SEK": 10,
+	"SGD": 2,
+}
+
+// Retries of a placeOrders call lost to a network error, the exchange
+// deduplicates retries sharing a customerRef for 60 seconds
+var PlaceOrdersRetries = 2
+
+// Delay between placeOrders retries
+var PlaceOrdersRetryDelay = 500 * time.Millisecond
+
+// Maximum length of customerRef and customerOrderRef accepted by the exchange
+const maxCustomerRefLength = 32
+
+// OrderExecutor places and manages orders, API sends them to the exchange
+// and PaperExecutor simulates them
+type OrderExecutor interface {
+	PlaceOrders(marketID string, instructions []PlaceInstruction, options Options) (PlaceExecutionReport, error)
+	CancelOrders(marketID string, instructions []CancelInstruction, options Options) (CancelExecutionReport, error)
+	UpdateOrders(marketID string, instructions []UpdateInstruction, options Options) (UpdateExecutionReport, error)
+	ReplaceOrders(marketID string, instructions []ReplaceInstruction, options Options) (ReplaceExecutionReport, error)
+}
+
+// InstructionError reports an instruction rejected before it was sent
+type InstructionError struct {
+	Index  int
+	Reason string
+}
+
+func (err *InstructionError) Error() string {
+	return fmt.Sprintf("Invalid instruction %d: %s", err.Index, err.Reason)
+}
+
+// priceLadderOption takes the priceLadder option off options, it is only
+// used to validate prices and not sent to the exchange. The option is a
+// PriceLadder or the MarketDescription of the market.
+func priceLadderOption(options Options) (*PriceLadder, Options, error) {
+	value, ok := options["priceLadder"]
+
+	if !ok {
+		return nil, options, nil
+	}
+
+	var rest = Options{}
+
+	for k, v := range options {
+		if k != "priceLadder" {
+			rest[k] = v
+		}
+	}
+
+	switch ladder := value.(type) {
+	case PriceLadder:
+		return &ladder, rest, nil
+	case *PriceLadder:
+		if ladder != nil {
+			return ladder, rest, nil
+		}
+	case MarketDescription:
+		priceLadder := ladder.PriceLadder()
+		return &priceLadder, rest, nil
+	case *MarketDescription:
+		if ladder != nil {
+			priceLadder := ladder.PriceLadder()
+			return &priceLadder, rest, nil
+		}
+	}
+
+	return nil, rest, fmt.Errorf("Invalid priceLadder option of type %T", value)
+}
+
+// isValidPrice checks price against the market's ladder. Without a ladder
+// only the odds range of the CLASSIC and FINEST ladders is checked, line
+// markets must pass their ladder.
+func isValidPrice(ladder *PriceLadder, price float64) bool {
+	if ladder == nil {
+		return price >= float64(ClassicPriceLadder.Min()) && price <= float64(ClassicPriceLadder.Max())
+	}
+
+	return ladder.IsValid(Price(price))
+}
+
+func minimumStake(currency string) float64 {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return MinimumStakes[currency]
+}
+
+func validatePlaceInstruction(instruction PlaceInstruction, currency string, ladder *PriceLadder) string {
+	if instruction.Side != SideBack && instruction.Side != SideLay {
+		return fmt.Sprintf("unknown side `%s`", instruction.Side)
+	}
+
+	minStake := minimumStake(currency)
+
+	switch instruction.OrderType {
+	case OrderTypeLimit:
+		order := instruction.LimitOrder
+
+		if order == nil || instruction.LimitOnCloseOrder != nil || instruction.MarketOnCloseOrder != nil {
+			return "LIMIT order requires limitOrder only"
+		}
+
+		if !isValidPrice(ladder, order.Price) {
+			return fmt.Sprintf("price %v is not on the price ladder", order.Price)
+		}
+
+		if order.BetTargetType == "" && order.Size < minStake {
+			return fmt.Sprintf("size %v is below the minimum stake %v", order.Size, minStake)
+		}
+
+		if order.BetTargetType != "" && order.BetTargetSize <= 0 {
+			return "betTargetSize is required with betTargetType"
+		}
+
+		if order.TimeInForce == "" && !order.PersistenceType.IsValid() {
+			return fmt.Sprintf("unknown persistence type `%s`", order.PersistenceType)
+		}
+	case OrderTypeLimitOnClose:
+		order := instruction.LimitOnCloseOrder
+
+		if order == nil || instruction.LimitOrder != nil || instruction.MarketOnCloseOrder != nil {
+			return "LIMIT_ON_CLOSE order requires limitOnCloseOrder only"
+		}
+
+		if !isValidPrice(ladder, order.Price) {
+			return fmt.Sprintf("price %v is not on the price ladder", order.Price)
+		}
+
+		if order.Liability < minStake {
+			return fmt.Sprintf("liability %v is below the minimum stake %v", order.Liability, minStake)
+		}
+	case OrderTypeMarketOnClose:
+		order := instruction.MarketOnCloseOrder
+
+		if order == nil || instruction.LimitOrder != nil || instruction.LimitOnCloseOrder != nil {
+			return "MARKET_ON_CLOSE order requires marketOnCloseOrder only"
+		}
+
+		if order.Liability < minStake {
+			return fmt.Sprintf("liability %v is below the minimum stake %v", order.Liability, minStake)
+		}
+	default:
+		return fmt.Sprintf("unknown order type `%s`", instruction.OrderType)
+	}
+
+	return ""
+}
+
+func validatePlaceInstructions(instructions []PlaceInstruction, currency string, ladder *PriceLadder) error {
+	for i, instruction := range instructions {
+		if reason := validatePlaceInstruction(instruction, currency, ladder); reason != "" {
+			return &InstructionError{Index: i, Reason: reason}
+		}
+	}
+
+	return nil
+}
+
+func validateCancelInstructions(marketID string, instructions []CancelInstruction) error {
+	if marketID == "" && len(instructions) > 0 {
+		return &InstructionError{Index: 0, Reason: "marketId is required to cancel instructions"}
+	}
+
+	for i, instruction := range instructions {
+		if instruction.BetID == "" {
+			return &InstructionError{Index: i, Reason: "betId is required"}
+		}
+
+		if instruction.SizeReduction < 0 {
+			return &InstructionError{Index: i, Reason: "sizeReduction must not be negative"}
+		}
+	}
+
+	return nil
+}
+
+func validateReplaceInstructions(instructions []ReplaceInstruction, ladder *PriceLadder) error {
+	for i, instruction := range instructions {
+		if instruction.BetID == "" {
+			return &InstructionError{Index: i, Reason: "betId is required"}
+		}
+
+		if !isValidPrice(ladder, instruction.NewPrice) {
+			return &InstructionError{Index: i, Reason: fmt.Sprintf("price %v is not on the price ladder", instruction.NewPrice)}
+		}
+	}
+
+	return nil
+}
+
+func validateUpdateInstructions(instructions []UpdateInstruction) error {
+	for i, instruction := range instructions {
+		if instruction.BetID == "" {
+			return &InstructionError{Index: i, Reason: "betId is required"}
+		}
+
+		if !instruction.NewPersistenceType.IsValid() {
+			return &InstructionError{Index: i, Reason: fmt.Sprintf("unknown persistence type `%s`", instruction.NewPersistenceType)}
+		}
+	}
+
+	return nil
+}
+
+// chunkBounds splits length items into [from, to) ranges of at most size
+// items, always returning at least one range. A size below one does not
+// split the items.
+func chunkBounds(length, size int) [][2]int {
+	var bounds = [][2]int{}
+
+	if size < 1 {
+		size = length
+	}
+
+	for from := 0; from < length; from += size {
+		to := from + size
+
+		if to > length {
+			to = length
+		}
+
+		bounds = append(bounds, [2]int{from, to})
+	}
+
+	if len(bounds) == 0 {
+		bounds = append(bounds, [2]int{0, 0})
+	}
+
+	return bounds
+}
+
+// chunkOptions gives every chunk after the first its own customerRef so the
+// exchange does not reject it as a duplicate transaction
+func chunkOptions(options Options, chunk int) Options {
+	customerRef, ok := options["customerRef"].(string)
+
+	if chunk == 0 || !ok || customerRef == "" {
+		return options
+	}
+
+	suffix := fmt.Sprintf("-%d", chunk)
+
+	if len(customerRef)+len(suffix) > maxCustomerRefLength {
+		customerRef = customerRef[:maxCustomerRefLength-len(suffix)]
+	}
+
+	return options.Merge(Options{"customerRef": customerRef + suffix})
+}
+
+func mergeExecutionStatus(status, chunkStatus string) string {
+	if status == "" || status == chunkStatus {
+		return chunkStatus
+	}
+
+	return "PROCESSED_WITH_ERRORS"
+}
+
+func mergeErrorCode(errorCode, chunkErrorCode string) string {
+	if errorCode == "" {
+		return chunkErrorCode
+	}
+
+	return errorCode
+}
+
+func (report *PlaceExecutionReport) merge(chunk PlaceExecutionReport) {
+	if report.Status == "" {
+		report.CustomerRef = chunk.CustomerRef
+	}
+
+	report.Status = mergeExecutionStatus(report.Status, chunk.Status)
+	report.ErrorCode = mergeErrorCode(report.ErrorCode, chunk.ErrorCode)
+	report.MarketID = chunk.MarketID
+	report.InstructionReports = append(report.InstructionReports, chunk.InstructionReports...)
+}
+
+func (report *CancelExecutionReport) merge(chunk CancelExecutionReport) {
+	if report.Status == "" {
+		report.CustomerRef = chunk.CustomerRef
+	}
+
+	report.Status = mergeExecutionStatus(report.Status, chunk.Status)
+	report.ErrorCode = mergeErrorCode(report.ErrorCode, chunk.ErrorCode)
+	report.MarketID = chunk.MarketID
+	report.InstructionReports = append(report.InstructionReports, chunk.InstructionReports...)
+}
+
+func (report *ReplaceExecutionReport) merge(chunk ReplaceExecutionReport) {
+	if report.Status == "" {
+		report.CustomerRef = chunk.CustomerRef
+	}
+
+	report.Status = mergeExecutionStatus(report.Status, chunk.Status)
+	report.ErrorCode = mergeErrorCode(report.ErrorCode, chunk.ErrorCode)
+	report.MarketID = chunk.MarketID
+	report.InstructionReports = append(report.InstructionReports, chunk.InstructionReports...)
+}
+
+func (report *UpdateExecutionReport) merge(chunk UpdateExecutionReport) {
+	if report.Status == "" {
+		report.CustomerRef = chunk.CustomerRef
+	}
+
+	report.Status = mergeExecutionStatus(report.Status, chunk.Status)
+	report.ErrorCode = mergeErrorCode(report.ErrorCode, chunk.ErrorCode)
+	report.MarketID = chunk.MarketID
+	report.InstructionReports = append(report.InstructionReports, chunk.InstructionReports...)
+}
+
+func newCustomerRef() string {
+	var ref = make([]byte, maxCustomerRefLength/2)
+
+	if _, err := rand.Read(ref); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(ref)
+}
+
+// withCustomerRefs makes sure the call carries a customerRef and every
+// instruction a customerOrderRef, so a retried call is deduplicated by the
+// exchange and its instructions can be found in the current orders
+func withCustomerRefs(options Options, instructions []PlaceInstruction) (Options, []PlaceInstruction) {
+	if customerRef, ok := options["customerRef"].(string); !ok || customerRef == "" {
+		options = options.Merge(Options{"customerRef": newCustomerRef()})
+	}
+
+	var referenced = make([]PlaceInstruction, len(instructions))
+
+	for i, instruction := range instructions {
+		if instruction.CustomerOrderRef == "" {
+			instruction.CustomerOrderRef = newCustomerRef()
+		}
+
+		referenced[i] = instruction
+	}
+
+	return options, referenced
+}
+
+// isTransportError reports errors that leave the outcome of a call unknown,
+// a truncated or undecodable response body may follow a placed order
+func isTransportError(err error) bool {
+	switch err.(type) {
+	case net.Error, *json.SyntaxError:
+		return true
+	}
+
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// placeOrdersWithRetry retries calls lost to network errors with the same
+// customerRef and falls back to reconciliation when the outcome is unknown
+func (api *API) placeOrdersWithRetry(marketID string, instructions []PlaceInstruction, options Options) (report PlaceExecutionReport, err error) {
+	var placeOrdersOptions = extendOptions(Options{"marketId": marketID, "instructions": instructions}, options)
+
+	for attempt := 0; ; attempt++ {
+		report = PlaceExecutionReport{}
+		err = api.doRequest(placeOrders, &report, placeOrdersOptions)
+
+		if err == nil && report.ErrorCode != "DUPLICATE_TRANSACTION" {
+			return report, nil
+		}
+
+		if err != nil && !isTransportError(err) {
+			return report, err
+		}
+
+		if err == nil || attempt >= PlaceOrdersRetries {
+			break
+		}
+
+		time.Sleep(PlaceOrdersRetryDelay)
+	}
+
+	reconciled, reconcileErr := api.ReconcilePlaceOrders(marketID, instructions)
+
+	if reconcileErr != nil {
+		if err == nil {
+			err = reconcileErr
+		}
+
+		return report, err
+	}
+
+	reconciled.CustomerRef, _ = options["customerRef"].(string)
+
+	if reconciled.Status == "FAILURE" && err != nil {
+		return reconciled, err
+	}
+
+	return reconciled, nil
+}
+
+// ReconcilePlaceOrders looks the instructions up by customerOrderRef in the
+// current orders, instructions that did not land are reported as TIMEOUT
+func (api *API) ReconcilePlaceOrders(marketID string, instructions []PlaceInstruction) (result PlaceExecutionReport, err error) {
+	var customerOrderRefs []string
+
+	for _, instruction := range instructions {
+		if instruction.CustomerOrderRef != "" {
+			customerOrderRefs = append(customerOrderRefs, instruction.CustomerOrderRef)
+		}
+	}
+
+	var orders = map[string]CurrentOrderSumary{}
+
+	if len(customerOrderRefs) > 0 {
+		report, err := api.ListCurrentOrdersWith(ListCurrentOrdersRequest{MarketIDs: []string{marketID}, CustomerOrderRefs: customerOrderRefs})
+
+		if err != nil {
+			return result, err
+		}
+
+		for _, order := range report.CurrentOrders {
+			orders[order.CustomerOrderRef] = order
+		}
+	}
+
+	result.MarketID = marketID
+
+	for _, instruction := range instructions {
+		order, ok := orders[instruction.CustomerOrderRef]
+
+		if !ok || instruction.CustomerOrderRef == "" {
+			result.Status = mergeExecutionStatus(result.Status, "FAILURE")
+			result.InstructionReports = append(result.InstructionReports, PlaceInstructionReport{Status: "TIMEOUT", Instruction: instruction})
+			continue
+		}
+
+		result.Status = mergeExecutionStatus(result.Status, "SUCCESS")
+		result.InstructionReports = append(result.InstructionReports, PlaceInstructionReport{
+			Status:              "SUCCESS",
+			OrderStatus:         order.Status,
+			Instruction:         instruction,
+			BetID:               order.BetID,
+			PlacedDate:          order.PlacedDate,
+			AveragePriceMatched: order.AveragePriceMatched,
+			SizeMatched:         order.SizeMatched,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,401 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func getTestLimitInstruction(price, size float64) PlaceInstruction {
+	return PlaceInstruction{
+		OrderType:   OrderTypeLimit,
+		SelectionID: 47972,
+		Side:        SideBack,
+		LimitOrder:  &LimitOrder{Size: size, Price: price, PersistenceType: PersistenceTypeLapse},
+	}
+}
+
+func TestIsValidPrice(t *testing.T) {
+	var cases = map[float64]bool{
+		1.01:  true,
+		1.015: false,
+		1.99:  true,
+		2.02:  true,
+		2.03:  false,
+		3.05:  true,
+		3.07:  false,
+		4.1:   true,
+		6.2:   true,
+		6.3:   false,
+		10.5:  true,
+		25:    true,
+		32:    true,
+		33:    false,
+		55:    true,
+		110:   true,
+		115:   false,
+		1000:  true,
+		1010:  false,
+		1:     false,
+		0.5:   false,
+		-2:    false,
+	}
+
+	for price, valid := range cases {
+		if isValidPrice(&ClassicPriceLadder, price) != valid {
+			t.Errorf("isValidPrice(%v) should be %v", price, valid)
+		}
+	}
+
+	if !isValidPrice(nil, 3.07) || !isValidPrice(nil, 1.01) || !isValidPrice(nil, 1000) {
+		t.Error("Odds should only be range checked without a ladder")
+	}
+
+	for _, price := range []float64{-2, 0, 1, 1001} {
+		if isValidPrice(nil, price) {
+			t.Errorf("isValidPrice(nil, %v) should be false", price)
+		}
+	}
+}
+
+func TestValidatePlaceInstructions(t *testing.T) {
+	var invalid = []PlaceInstruction{
+		getTestLimitInstruction(3.07, 2),
+		getTestLimitInstruction(3.05, 0.5),
+		{OrderType: OrderTypeLimit, SelectionID: 1, Side: "BOTH", LimitOrder: &LimitOrder{Size: 2, Price: 2, PersistenceType: PersistenceTypeLapse}},
+		{OrderType: OrderTypeLimit, SelectionID: 1, Side: SideLay, MarketOnCloseOrder: &MarketOnCloseOrder{Liability: 10}},
+		{OrderType: OrderTypeMarketOnClose, SelectionID: 1, Side: SideLay, LimitOrder: &LimitOrder{Size: 2, Price: 2}},
+		{OrderType: OrderTypeLimitOnClose, SelectionID: 1, Side: SideLay, LimitOnCloseOrder: &LimitOnCloseOrder{Liability: 10, Price: 2.01}},
+		{OrderType: OrderTypeLimit, SelectionID: 1, Side: SideLay, LimitOrder: &LimitOrder{Size: 2, Price: 2, PersistenceType: "FOREVER"}},
+	}
+
+	for i, instruction := range invalid {
+		err := validatePlaceInstructions([]PlaceInstruction{instruction}, "GBP", &ClassicPriceLadder)
+
+		if _, ok := err.(*InstructionError); !ok {
+			t.Errorf("Instruction %d should be rejected, got %v", i, err)
+		}
+	}
+
+	var valid = []PlaceInstruction{
+		getTestLimitInstruction(3.05, 2),
+		{OrderType: OrderTypeLimitOnClose, SelectionID: 1, Side: SideLay, LimitOnCloseOrder: &LimitOnCloseOrder{Liability: 10, Price: 2.02}},
+		{OrderType: OrderTypeMarketOnClose, SelectionID: 1, Side: SideBack, MarketOnCloseOrder: &MarketOnCloseOrder{Liability: 10}},
+		{OrderType: OrderTypeLimit, SelectionID: 1, Side: SideBack, LimitOrder: &LimitOrder{Price: 2, TimeInForce: "FILL_OR_KILL", BetTargetType: "PAYOUT", BetTargetSize: 10}},
+	}
+
+	if err := validatePlaceInstructions(valid, "", &ClassicPriceLadder); err != nil {
+		t.Error(err)
+	}
+
+	if err := validatePlaceInstructions(invalid[:1], "GBP", nil); err != nil {
+		t.Errorf("Price should not be checked without a ladder, got %v", err)
+	}
+}
+
+func TestPlaceOrdersValidationSkipsRequest(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		t.Errorf("Unexpected request %s", call.Method)
+		return nil
+	})
+
+	instructions := []PlaceInstruction{getTestLimitInstruction(2, 2), getTestLimitInstruction(2.01, 0.1)}
+	_, err := api.PlaceOrders("1.114363660", instructions, Options{})
+
+	if err, ok := err.(*InstructionError); !ok || err.Index != 1 {
+		t.Errorf("Expected instruction error for index 1, got %v", err)
+	}
+
+	_, err = api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(3.07, 2)}, Options{"priceLadder": ClassicPriceLadder})
+
+	if err, ok := err.(*InstructionError); !ok || err.Index != 0 {
+		t.Errorf("Expected instruction error for index 0, got %v", err)
+	}
+
+	_, err = api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(-2, 2)}, Options{})
+
+	if err, ok := err.(*InstructionError); !ok || err.Index != 0 {
+		t.Errorf("Expected instruction error for a negative price, got %v", err)
+	}
+
+	_, err = api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(2, 2)}, Options{"priceLadder": "CLASSIC"})
+
+	if err == nil {
+		t.Error("Expected an error for an unsupported priceLadder option")
+	}
+
+	_, err = api.CancelOrders("", []CancelInstruction{{BetID: "1"}}, Options{})
+
+	if err, ok := err.(*InstructionError); !ok || err.Index != 0 {
+		t.Errorf("Expected instruction error for a blank marketId, got %v", err)
+	}
+}
+
+func TestPlaceOrdersSplitting(t *testing.T) {
+	var customerRefs []string
+	var sizes []int
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var params struct {
+			CustomerRef  string             `json:"customerRef"`
+			Instructions []PlaceInstruction `json:"instructions"`
+		}
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		customerRefs = append(customerRefs, params.CustomerRef)
+		sizes = append(sizes, len(params.Instructions))
+
+		var report = PlaceExecutionReport{CustomerRef: params.CustomerRef, Status: "SUCCESS", MarketID: "1.114363660"}
+
+		for _, instruction := range params.Instructions {
+			report.InstructionReports = append(report.InstructionReports, PlaceInstructionReport{Status: "SUCCESS", Instruction: instruction})
+		}
+
+		if len(customerRefs) == 2 {
+			report.Status = "FAILURE"
+			report.ErrorCode = "MARKET_SUSPENDED"
+		}
+
+		return report
+	})
+
+	var instructions []PlaceInstruction
+
+	for i := 0; i < 250; i++ {
+		instructions = append(instructions, getTestLimitInstruction(2, float64(i+2)))
+	}
+
+	report, err := api.PlaceOrders("1.114363660", instructions, Options{"customerRef": "ref"})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(sizes) != 2 || sizes[0] != 200 || sizes[1] != 50 {
+		t.Errorf("Unexpected chunks %v", sizes)
+	}
+
+	if len(customerRefs) != 2 || customerRefs[0] != "ref" || customerRefs[1] != "ref-1" {
+		t.Errorf("Unexpected customer refs %v", customerRefs)
+	}
+
+	if report.CustomerRef != "ref" || report.Status != "PROCESSED_WITH_ERRORS" || report.ErrorCode != "MARKET_SUSPENDED" {
+		t.Errorf("Unexpected merged report %+v", report)
+	}
+
+	if len(report.InstructionReports) != 250 || report.InstructionReports[249].Instruction.LimitOrder.Size != 251 {
+		t.Errorf("Instruction reports were not merged in order")
+	}
+}
+
+func TestCancelOrdersSplitting(t *testing.T) {
+	var calls int
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		calls++
+		return CancelExecutionReport{Status: "SUCCESS", InstructionReports: []CancelInstructionReport{{Status: "SUCCESS"}}}
+	})
+
+	var instructions []CancelInstruction
+
+	for i := 0; i < 61; i++ {
+		instructions = append(instructions, CancelInstruction{BetID: "31242604945"})
+	}
+
+	report, err := api.CancelOrders("1.114363660", instructions, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if calls != 2 || report.Status != "SUCCESS" || len(report.InstructionReports) != 2 {
+		t.Errorf("Unexpected %d calls with report %+v", calls, report)
+	}
+}
+
+func TestPlaceOrdersRetryReconciles(t *testing.T) {
+	var customerRefs []string
+	var customerOrderRefs []string
+
+	retryDelay := PlaceOrdersRetryDelay
+	PlaceOrdersRetryDelay = 0
+	defer func() { PlaceOrdersRetryDelay = retryDelay }()
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		switch call.Method {
+		case placeOrders:
+			var params struct {
+				CustomerRef  string             `json:"customerRef"`
+				Instructions []PlaceInstruction `json:"instructions"`
+			}
+
+			if err := json.Unmarshal(call.Params, &params); err != nil {
+				t.Error(err)
+			}
+
+			customerRefs = append(customerRefs, params.CustomerRef)
+			customerOrderRefs = append(customerOrderRefs, params.Instructions[0].CustomerOrderRef)
+
+			if len(customerRefs) == 1 {
+				return errMockDisconnect
+			}
+
+			return PlaceExecutionReport{Status: "FAILURE", ErrorCode: "DUPLICATE_TRANSACTION"}
+		case listCurrentOrders:
+			return CurrentOrderSummaryReport{
+				CurrentOrders: []CurrentOrderSumary{
+					{BetID: "31242604945", MarketID: "1.114363660", Status: "EXECUTABLE", CustomerOrderRef: customerOrderRefs[0]},
+				},
+			}
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+
+	report, err := api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(2, 2)}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(customerRefs) != 2 || customerRefs[0] == "" || customerRefs[0] != customerRefs[1] {
+		t.Errorf("Retry should reuse the generated customer ref, got %v", customerRefs)
+	}
+
+	if customerOrderRefs[0] == "" || customerOrderRefs[0] != customerOrderRefs[1] {
+		t.Errorf("Retry should reuse the generated customer order ref, got %v", customerOrderRefs)
+	}
+
+	if report.Status != "SUCCESS" || report.CustomerRef != customerRefs[0] || report.InstructionReports[0].BetID != "31242604945" {
+		t.Errorf("Unexpected reconciled report %+v", report)
+	}
+}
+
+func TestPlaceOrdersRetriesTruncatedResponse(t *testing.T) {
+	var calls int
+
+	retryDelay := PlaceOrdersRetryDelay
+	PlaceOrdersRetryDelay = 0
+	defer func() { PlaceOrdersRetryDelay = retryDelay }()
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		switch call.Method {
+		case placeOrders:
+			calls++
+
+			if calls == 1 {
+				return errMockTruncate
+			}
+
+			return PlaceExecutionReport{Status: "SUCCESS", InstructionReports: []PlaceInstructionReport{{Status: "SUCCESS", BetID: "31242604945"}}}
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+
+	report, err := api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(2, 2)}, Options{})
+
+	if err != nil || calls != 2 || report.Status != "SUCCESS" || report.InstructionReports[0].BetID != "31242604945" {
+		t.Errorf("Expected the truncated call to be retried, got %d calls, %+v, %v", calls, report, err)
+	}
+}
+
+func TestPlaceOrdersRetriesStalledResponse(t *testing.T) {
+	var calls int
+
+	clientTimeout := ClientTimeout
+	ClientTimeout = 50 * time.Millisecond
+	defer func() { ClientTimeout = clientTimeout }()
+
+	retryDelay := PlaceOrdersRetryDelay
+	PlaceOrdersRetryDelay = 0
+	defer func() { PlaceOrdersRetryDelay = retryDelay }()
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		switch call.Method {
+		case placeOrders:
+			calls++
+
+			if calls == 1 {
+				return errMockStall
+			}
+
+			return PlaceExecutionReport{Status: "SUCCESS", InstructionReports: []PlaceInstructionReport{{Status: "SUCCESS", BetID: "31242604945"}}}
+		}
+
+		t.Errorf("Unexpected method %s", call.Method)
+		return nil
+	})
+
+	report, err := api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(2, 2)}, Options{})
+
+	if err != nil || calls != 2 || report.Status != "SUCCESS" || report.InstructionReports[0].BetID != "31242604945" {
+		t.Errorf("Expected the stalled call to be retried, got %d calls, %+v, %v", calls, report, err)
+	}
+}
+
+func TestReconcilePlaceOrdersMissing(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		return CurrentOrderSummaryReport{}
+	})
+
+	instruction := getTestLimitInstruction(2, 2)
+	instruction.CustomerOrderRef = "order-1"
+
+	report, err := api.ReconcilePlaceOrders("1.114363660", []PlaceInstruction{instruction})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if report.Status != "FAILURE" || report.InstructionReports[0].Status != "TIMEOUT" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestPlaceOrdersFinestPriceLadder(t *testing.T) {
+	var calls int
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		var params map[string]interface{}
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		if _, ok := params["priceLadder"]; ok {
+			t.Error("The priceLadder option should not be sent")
+		}
+
+		calls++
+		return PlaceExecutionReport{Status: "SUCCESS", InstructionReports: []PlaceInstructionReport{{Status: "SUCCESS"}}}
+	})
+
+	description := &MarketDescription{PriceLadderDescription: &PriceLadderDescription{Type: PriceLadderFinest}}
+	_, err := api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(3.07, 2)}, Options{"priceLadder": description})
+
+	if err != nil || calls != 1 {
+		t.Errorf("Expected 3.07 to be placed on a FINEST market, got %v", err)
+	}
+
+	_, err = api.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(3.075, 2)}, Options{"priceLadder": description})
+
+	if _, ok := err.(*InstructionError); !ok || calls != 1 {
+		t.Errorf("Expected 3.075 to be rejected, got %v", err)
+	}
+
+	_, err = api.ReplaceOrders("1.114363660", []ReplaceInstruction{{BetID: "31242604945", NewPrice: 3.07}}, Options{"priceLadder": (MarketDescription{})})
+
+	if _, ok := err.(*InstructionError); !ok {
+		t.Errorf("Expected 3.07 to be rejected on a CLASSIC market, got %v", err)
+	}
+}
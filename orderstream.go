@@ -0,0 +1,524 @@
+package betfair
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderFilter selects the orders of an order subscription. Matched amounts
+// are split by customer strategy ref with PartitionMatchedByStrategyRef.
+type OrderFilter struct {
+	IncludeOverallPosition        *bool    `json:"includeOverallPosition,omitempty"`
+	CustomerStrategyRefs          []string `json:"customerStrategyRefs,omitempty"`
+	PartitionMatchedByStrategyRef bool     `json:"partitionMatchedByStrategyRef,omitempty"`
+}
+
+// OrderSubscriptionRequest selects the orders of a subscription, HeartbeatMs
+// and ConflateMs work as for MarketSubscriptionRequest
+type OrderSubscriptionRequest struct {
+	OrderFilter OrderFilter
+	HeartbeatMs int64
+	ConflateMs  int64
+}
+
+type orderSubscriptionMessage struct {
+	Op          string      `json:"op"`
+	ID          int64       `json:"id"`
+	InitialClk  string      `json:"initialClk,omitempty"`
+	Clk         string      `json:"clk,omitempty"`
+	HeartbeatMs int64       `json:"heartbeatMs,omitempty"`
+	ConflateMs  int64       `json:"conflateMs,omitempty"`
+	OrderFilter OrderFilter `json:"orderFilter"`
+}
+
+// StreamOrder is an order as the stream sends it, with abbreviated enums
+// and dates in milliseconds since the epoch
+type StreamOrder struct {
+	ID                    string  `json:"id"`
+	Price                 float64 `json:"p"`
+	Size                  float64 `json:"s"`
+	BSPLiability          float64 `json:"bsp"`
+	Side                  string  `json:"side"`
+	Status                string  `json:"status"`
+	PersistenceType       string  `json:"pt"`
+	OrderType             string  `json:"ot"`
+	PlacedDate            int64   `json:"pd"`
+	MatchedDate           int64   `json:"md"`
+	CancelledDate         int64   `json:"cd"`
+	LapsedDate            int64   `json:"ld"`
+	LapseStatusReasonCode string  `json:"lsrc"`
+	AveragePriceMatched   float64 `json:"avp"`
+	SizeMatched           float64 `json:"sm"`
+	SizeRemaining         float64 `json:"sr"`
+	SizeLapsed            float64 `json:"sl"`
+	SizeCancelled         float64 `json:"sc"`
+	SizeVoided            float64 `json:"sv"`
+	RegulatorAuthCode     string  `json:"rac"`
+	RegulatorCode         string  `json:"rc"`
+	CustomerOrderRef      string  `json:"rfo"`
+	CustomerStrategyRef   string  `json:"rfs"`
+}
+
+var streamSides = map[string]Side{"B": SideBack, "L": SideLay}
+
+var streamOrderStatuses = map[string]OrderStatus{"E": OrderStatusExecutable, "EC": OrderStatusExecutionComplete}
+
+var streamPersistenceTypes = map[string]PersistenceType{
+	"L":   PersistenceTypeLapse,
+	"P":   PersistenceTypePersist,
+	"MOC": PersistenceTypeMarketOnClose,
+}
+
+var streamOrderTypes = map[string]OrderType{
+	"L":   OrderTypeLimit,
+	"LOC": OrderTypeLimitOnClose,
+	"MOC": OrderTypeMarketOnClose,
+}
+
+func streamTime(milliseconds int64) time.Time {
+	return time.Unix(0, milliseconds*int64(time.Millisecond)).UTC()
+}
+
+// Summary converts the order to the type ListCurrentOrders returns
+func (order StreamOrder) Summary(marketID string, selectionID int64, handicap float64) CurrentOrderSumary {
+	var summary = CurrentOrderSumary{
+		BetID:               order.ID,
+		MarketID:            marketID,
+		SelectionID:         selectionID,
+		Handicap:            handicap,
+		PriceSize:           PriceSize{Price: order.Price, Size: order.Size},
+		BSPLiability:        order.BSPLiability,
+		Side:                streamSides[order.Side],
+		Status:              streamOrderStatuses[order.Status],
+		PersistenceType:     streamPersistenceTypes[order.PersistenceType],
+		OrderType:           streamOrderTypes[order.OrderType],
+		PlacedDate:          streamTime(order.PlacedDate),
+		AveragePriceMatched: order.AveragePriceMatched,
+		SizeMatched:         order.SizeMatched,
+		SizeRemaining:       order.SizeRemaining,
+		SizeLapsed:          order.SizeLapsed,
+		SizeCancelled:       order.SizeCancelled,
+		SizeVoided:          order.SizeVoided,
+		RegulatorAuthCode:   order.RegulatorAuthCode,
+		RegulatorCode:       order.RegulatorCode,
+		CustomerOrderRef:    order.CustomerOrderRef,
+		CustomerStrategyRef: order.CustomerStrategyRef,
+	}
+
+	if order.MatchedDate > 0 {
+		matchedDate := streamTime(order.MatchedDate)
+		summary.MatchedDate = &matchedDate
+	}
+
+	return summary
+}
+
+// StrategyMatchChange holds the matched ladders of one customer strategy ref
+type StrategyMatchChange struct {
+	MatchedBacks [][2]float64 `json:"mb"`
+	MatchedLays  [][2]float64 `json:"ml"`
+}
+
+// OrderRunnerChange carries the changed orders of a runner and its matched
+// [price, size] ladders, a full image replaces the runner
+type OrderRunnerChange struct {
+	ID              int64                          `json:"id"`
+	Handicap        float64                        `json:"hc"`
+	FullImage       bool                           `json:"fullImage"`
+	UnmatchedOrders []StreamOrder                  `json:"uo"`
+	MatchedBacks    [][2]float64                   `json:"mb"`
+	MatchedLays     [][2]float64                   `json:"ml"`
+	StrategyMatches map[string]StrategyMatchChange `json:"smc"`
+}
+
+type OrderMarketChange struct {
+	ID                 string              `json:"id"`
+	AccountID          int64               `json:"accountId"`
+	Closed             bool                `json:"closed"`
+	FullImage          bool                `json:"fullImage"`
+	OrderRunnerChanges []OrderRunnerChange `json:"orc"`
+}
+
+// OrderChangeMessage is an ocm message of the order subscription.
+// PublishTime is in milliseconds since the epoch, ReceivedAt is local.
+type OrderChangeMessage struct {
+	Op                 string              `json:"op"`
+	ID                 int64               `json:"id"`
+	ChangeType         string              `json:"ct"`
+	Clk                string              `json:"clk"`
+	InitialClk         string              `json:"initialClk"`
+	PublishTime        int64               `json:"pt"`
+	HeartbeatMs        int64               `json:"heartbeatMs"`
+	ConflateMs         int64               `json:"conflateMs"`
+	Status             int                 `json:"status"`
+	SegmentType        string              `json:"segmentType"`
+	OrderMarketChanges []OrderMarketChange `json:"oc"`
+	ReceivedAt         time.Time           `json:"-"`
+}
+
+// Latency returns the delay between publishing and receiving the message
+func (message OrderChangeMessage) Latency() time.Duration {
+	return message.ReceivedAt.Sub(streamTime(message.PublishTime))
+}
+
+// Stale reports whether the exchange flagged its data as out of date
+func (message OrderChangeMessage) Stale() bool {
+	return message.Status == streamStatusStale
+}
+
+// MatchedLadders are matched amounts by price
+type MatchedLadders struct {
+	MatchedBacks []PriceSize
+	MatchedLays  []PriceSize
+}
+
+// RunnerOrders is a snapshot of the cached orders of a runner. Orders keeps
+// orders after they complete, Unmatched only those still executable.
+type RunnerOrders struct {
+	MarketID        string
+	SelectionID     int64
+	Handicap        float64
+	Orders          []CurrentOrderSumary
+	Unmatched       []CurrentOrderSumary
+	Matched         MatchedLadders
+	StrategyMatches map[string]MatchedLadders
+}
+
+type matchedCache struct {
+	backs, lays pricePoints
+}
+
+func newMatchedCache() *matchedCache {
+	return &matchedCache{backs: pricePoints{}, lays: pricePoints{}}
+}
+
+func (matched *matchedCache) apply(backs, lays [][2]float64) {
+	matched.backs.apply(backs)
+	matched.lays.apply(lays)
+}
+
+func (matched *matchedCache) ladders() MatchedLadders {
+	return MatchedLadders{MatchedBacks: matched.backs.priceSizes(false), MatchedLays: matched.lays.priceSizes(false)}
+}
+
+type orderRunnerCache struct {
+	orders     map[string]StreamOrder
+	matched    *matchedCache
+	strategies map[string]*matchedCache
+}
+
+func newOrderRunnerCache() *orderRunnerCache {
+	return &orderRunnerCache{orders: map[string]StreamOrder{}, matched: newMatchedCache(), strategies: map[string]*matchedCache{}}
+}
+
+func (runner *orderRunnerCache) apply(change OrderRunnerChange) {
+	for _, order := range change.UnmatchedOrders {
+		runner.orders[order.ID] = order
+	}
+
+	runner.matched.apply(change.MatchedBacks, change.MatchedLays)
+
+	for strategyRef, strategyChange := range change.StrategyMatches {
+		strategy, ok := runner.strategies[strategyRef]
+
+		if !ok {
+			strategy = newMatchedCache()
+			runner.strategies[strategyRef] = strategy
+		}
+
+		strategy.apply(strategyChange.MatchedBacks, strategyChange.MatchedLays)
+	}
+}
+
+func (runner *orderRunnerCache) snapshot(marketID string, key runnerKey) RunnerOrders {
+	var snapshot = RunnerOrders{
+		MarketID:        marketID,
+		SelectionID:     key.selectionID,
+		Handicap:        key.handicap,
+		Matched:         runner.matched.ladders(),
+		StrategyMatches: map[string]MatchedLadders{},
+	}
+
+	for _, order := range runner.orders {
+		summary := order.Summary(marketID, key.selectionID, key.handicap)
+		snapshot.Orders = append(snapshot.Orders, summary)
+
+		if summary.Status == OrderStatusExecutable {
+			snapshot.Unmatched = append(snapshot.Unmatched, summary)
+		}
+	}
+
+	sortOrders(snapshot.Orders)
+	sortOrders(snapshot.Unmatched)
+
+	for strategyRef, strategy := range runner.strategies {
+		snapshot.StrategyMatches[strategyRef] = strategy.ladders()
+	}
+
+	return snapshot
+}
+
+// sortOrders orders by placed date, then bet id
+func sortOrders(orders []CurrentOrderSumary) {
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].PlacedDate.Equal(orders[j].PlacedDate) {
+			return orders[i].PlacedDate.Before(orders[j].PlacedDate)
+		}
+
+		return orders[i].BetID < orders[j].BetID
+	})
+}
+
+type orderMarketCache struct {
+	closed  bool
+	runners map[runnerKey]*orderRunnerCache
+}
+
+// OrderCache holds the orders of an order subscription, rebuilt from the
+// images and deltas of the stream. Closed markets and completed orders stay
+// cached until Prune removes them, so long running subscriptions should
+// prune once they have read what they need.
+type OrderCache struct {
+	m       sync.RWMutex
+	markets map[string]*orderMarketCache
+	stale   bool
+}
+
+func newOrderCache() *OrderCache {
+	return &OrderCache{markets: map[string]*orderMarketCache{}}
+}
+
+func (cache *OrderCache) apply(message OrderChangeMessage) {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	cache.stale = message.Stale()
+
+	for _, change := range message.OrderMarketChanges {
+		market, ok := cache.markets[change.ID]
+
+		if !ok || change.FullImage {
+			market = &orderMarketCache{runners: map[runnerKey]*orderRunnerCache{}}
+			cache.markets[change.ID] = market
+		}
+
+		market.closed = change.Closed
+
+		for _, runnerChange := range change.OrderRunnerChanges {
+			key := runnerKey{runnerChange.ID, runnerChange.Handicap}
+			runner, ok := market.runners[key]
+
+			if !ok || runnerChange.FullImage {
+				runner = newOrderRunnerCache()
+				market.runners[key] = runner
+			}
+
+			runner.apply(runnerChange)
+		}
+	}
+}
+
+func (cache *OrderCache) reset() {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	cache.markets = map[string]*orderMarketCache{}
+}
+
+// MarketOrders returns snapshots of the runners with orders in a market,
+// ordered by selection id
+func (cache *OrderCache) MarketOrders(marketID string) []RunnerOrders {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+
+	if !ok {
+		return nil
+	}
+
+	var keys = make([]runnerKey, 0, len(market.runners))
+
+	for key := range market.runners {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].selectionID != keys[j].selectionID {
+			return keys[i].selectionID < keys[j].selectionID
+		}
+
+		return keys[i].handicap < keys[j].handicap
+	})
+
+	var result = make([]RunnerOrders, len(keys))
+
+	for i, key := range keys {
+		result[i] = market.runners[key].snapshot(marketID, key)
+	}
+
+	return result
+}
+
+// RunnerOrders returns a snapshot of the orders of a runner
+func (cache *OrderCache) RunnerOrders(marketID string, selectionID int64, handicap float64) (RunnerOrders, bool) {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+
+	if !ok {
+		return RunnerOrders{}, false
+	}
+
+	key := runnerKey{selectionID, handicap}
+	runner, ok := market.runners[key]
+
+	if !ok {
+		return RunnerOrders{}, false
+	}
+
+	return runner.snapshot(marketID, key), true
+}
+
+// CurrentOrders returns every cached order of the given markets, of every
+// market when none are given, ordered by placed date
+func (cache *OrderCache) CurrentOrders(marketIDs ...string) []CurrentOrderSumary {
+	cache.m.RLock()
+	var markets = marketIDs
+
+	if len(markets) == 0 {
+		for marketID := range cache.markets {
+			markets = append(markets, marketID)
+		}
+	}
+
+	cache.m.RUnlock()
+
+	var orders []CurrentOrderSumary
+
+	for _, marketID := range markets {
+		for _, runner := range cache.MarketOrders(marketID) {
+			orders = append(orders, runner.Orders...)
+		}
+	}
+
+	sortOrders(orders)
+	return orders
+}
+
+// Stale reports whether the last message flagged the exchange's data as out
+// of date
+func (cache *OrderCache) Stale() bool {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	return cache.stale
+}
+
+// Prune drops closed markets and execution complete orders from the cache,
+// the matched ladders of open markets keep what the removed orders matched.
+// It returns the number of orders removed.
+func (cache *OrderCache) Prune() int {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	var removed int
+
+	for marketID, market := range cache.markets {
+		if market.closed {
+			for _, runner := range market.runners {
+				removed += len(runner.orders)
+			}
+
+			delete(cache.markets, marketID)
+			continue
+		}
+
+		for _, runner := range market.runners {
+			for betID, order := range runner.orders {
+				if streamOrderStatuses[order.Status] == OrderStatusExecutionComplete {
+					delete(runner.orders, betID)
+					removed++
+				}
+			}
+		}
+	}
+
+	return removed
+}
+
+// MarketClosed reports whether the exchange closed a cached market
+func (cache *OrderCache) MarketClosed(marketID string) bool {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	market, ok := cache.markets[marketID]
+	return ok && market.closed
+}
+
+type orderSubscription struct {
+	streamSubscription
+	request OrderSubscriptionRequest
+	cache   *OrderCache
+}
+
+func (subscription *orderSubscription) message() orderSubscriptionMessage {
+	return orderSubscriptionMessage{
+		Op:          "orderSubscription",
+		ID:          subscription.id,
+		InitialClk:  subscription.initialClk,
+		Clk:         subscription.clk,
+		HeartbeatMs: subscription.request.HeartbeatMs,
+		ConflateMs:  subscription.request.ConflateMs,
+		OrderFilter: subscription.request.OrderFilter,
+	}
+}
+
+// SubscribeOrders replaces the order subscription of the stream and returns
+// the cache its changes are applied to
+func (stream *Stream) SubscribeOrders(request OrderSubscriptionRequest) (*OrderCache, error) {
+	var subscription = &orderSubscription{streamSubscription: newStreamSubscription(stream.nextID(), request.HeartbeatMs), request: request, cache: newOrderCache()}
+
+	stream.m.Lock()
+	previous := stream.orderSubscription
+	stream.orderSubscription = subscription
+	stream.m.Unlock()
+
+	_, err := stream.request(subscription.id, subscription.message())
+
+	if err != nil {
+		stream.m.Lock()
+
+		if stream.orderSubscription == subscription {
+			stream.orderSubscription = previous
+		}
+
+		stream.m.Unlock()
+		return nil, err
+	}
+
+	return subscription.cache, nil
+}
+
+// handleOrderChange applies a message of the current subscription to its
+// cache and emits it, messages of replaced subscriptions are dropped
+func (stream *Stream) handleOrderChange(message OrderChangeMessage) {
+	stream.m.Lock()
+	subscription := stream.orderSubscription
+	stream.m.Unlock()
+
+	if subscription == nil || subscription.id != message.ID {
+		return
+	}
+
+	if subscription.fullImage(message.ChangeType, message.SegmentType) {
+		subscription.cache.reset()
+		stream.emitEvent(CacheResetEvent{Op: "ocm", SubscriptionID: subscription.id})
+	}
+
+	subscription.update(message.InitialClk, message.Clk, message.HeartbeatMs)
+	subscription.cache.apply(message)
+	stream.emit(message)
+}
@@ -0,0 +1,92 @@
+package betfair
+
+import "testing"
+
+func TestStreamOrderCache(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+
+		var subscription orderSubscriptionMessage
+		server.read(&subscription)
+
+		if subscription.Op != "orderSubscription" || !subscription.OrderFilter.PartitionMatchedByStrategyRef || subscription.OrderFilter.CustomerStrategyRefs[0] != "scalper" {
+			t.Errorf("Unexpected subscription %+v", subscription)
+		}
+
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS","connectionClosed":false}`)
+		server.write(`{"op":"ocm","id":2,"initialClk":"AAA","clk":"AAA","pt":1465903680000,"ct":"SUB_IMAGE","oc":[{"id":"1.120684740","fullImage":true,
+			"orc":[{"id":101,"fullImage":true,
+				"uo":[{"id":"7001","p":2.5,"s":10,"side":"B","status":"E","pt":"L","ot":"L","pd":1465903600000,"sm":0,"sr":10,"rfo":"ref-1","rfs":"scalper"},
+					{"id":"7002","p":2.6,"s":4,"side":"L","status":"E","pt":"P","ot":"L","pd":1465903610000,"sm":0,"sr":4,"rfs":"scalper"}]}]}]}`)
+		server.write(`{"op":"ocm","id":2,"clk":"AAB","pt":1465903681000,"oc":[{"id":"1.120684740",
+			"orc":[{"id":101,
+				"uo":[{"id":"7001","p":2.5,"s":10,"side":"B","status":"EC","pt":"L","ot":"L","pd":1465903600000,"md":1465903681000,"avp":2.5,"sm":10,"sr":0,"rfo":"ref-1","rfs":"scalper"}],
+				"mb":[[2.5,10]],"smc":{"scalper":{"mb":[[2.5,10]]}}}]}]}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cache, err := stream.SubscribeOrders(OrderSubscriptionRequest{OrderFilter: OrderFilter{CustomerStrategyRefs: []string{"scalper"}, PartitionMatchedByStrategyRef: true}})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for changes := 0; changes < 2; {
+		message, ok := <-stream.Messages()
+
+		if !ok {
+			t.Errorf("Stream stopped: %v", stream.Err())
+			return
+		}
+
+		if _, ok := message.(OrderChangeMessage); ok {
+			changes++
+		}
+	}
+
+	runner, ok := cache.RunnerOrders("1.120684740", 101, 0)
+
+	if !ok {
+		t.Error("Runner not cached")
+		return
+	}
+
+	if len(runner.Orders) != 2 || len(runner.Unmatched) != 1 || runner.Unmatched[0].BetID != "7002" || runner.Unmatched[0].Side != SideLay || runner.Unmatched[0].PersistenceType != PersistenceTypePersist {
+		t.Errorf("Unexpected orders %+v", runner)
+	}
+
+	matched := runner.Orders[0]
+
+	if matched.BetID != "7001" || matched.Status != OrderStatusExecutionComplete || matched.SizeMatched != 10 || matched.MatchedDate == nil || matched.CustomerOrderRef != "ref-1" || matched.MarketID != "1.120684740" {
+		t.Errorf("Unexpected matched order %+v", matched)
+	}
+
+	if len(runner.Matched.MatchedBacks) != 1 || runner.Matched.MatchedBacks[0] != (PriceSize{2.5, 10}) || runner.StrategyMatches["scalper"].MatchedBacks[0].Size != 10 {
+		t.Errorf("Unexpected matched ladders %+v", runner)
+	}
+
+	if orders := cache.CurrentOrders(); len(orders) != 2 || orders[1].BetID != "7002" {
+		t.Errorf("Unexpected current orders %+v", orders)
+	}
+
+	if removed := cache.Prune(); removed != 1 {
+		t.Errorf("Expected the completed order to be pruned, removed %d", removed)
+	}
+
+	if runner, _ := cache.RunnerOrders("1.120684740", 101, 0); len(runner.Orders) != 1 || runner.Matched.MatchedBacks[0].Size != 10 {
+		t.Errorf("Unexpected runner after pruning %+v", runner)
+	}
+
+	cache.apply(OrderChangeMessage{OrderMarketChanges: []OrderMarketChange{{ID: "1.120684740", Closed: true}}})
+
+	if !cache.MarketClosed("1.120684740") || cache.Prune() != 1 || len(cache.CurrentOrders()) != 0 || cache.MarketClosed("1.120684740") {
+		t.Error("Expected the closed market to be pruned")
+	}
+}
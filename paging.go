@@ -0,0 +1,39 @@
+package betfair
+
+// recordPager walks a listing call with fromRecord and recordCount. fetch
+// requests the page at fromRecord and returns its number of records and
+// the moreAvailable flag of the report.
+type recordPager struct {
+	fromRecord int
+	fetch      func(fromRecord int) (int, bool, error)
+	done       bool
+	err        error
+}
+
+// pageSize returns recordCount, or the default when the request does not set it
+func pageSize(recordCount, defaultSize int) int {
+	if recordCount == 0 {
+		return defaultSize
+	}
+
+	return recordCount
+}
+
+// next fetches the next page, false when every record was read or a request failed
+func (pager *recordPager) next() bool {
+	if pager.done || pager.err != nil {
+		return false
+	}
+
+	records, moreAvailable, err := pager.fetch(pager.fromRecord)
+
+	if err != nil {
+		pager.err = err
+		return false
+	}
+
+	pager.fromRecord += records
+	pager.done = !moreAvailable || records == 0
+
+	return records > 0
+}
@@ -0,0 +1,53 @@
+package betfair
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecordPager(t *testing.T) {
+	var fromRecords []int
+
+	pager := recordPager{fromRecord: 5, fetch: func(fromRecord int) (int, bool, error) {
+		fromRecords = append(fromRecords, fromRecord)
+
+		records := 25 - fromRecord
+
+		if records > 10 {
+			records = 10
+		}
+
+		return records, fromRecord+records < 25, nil
+	}}
+
+	var pages int
+
+	for pager.next() {
+		pages++
+	}
+
+	if pager.err != nil || pages != 2 || len(fromRecords) != 2 || fromRecords[1] != 15 || pager.fromRecord != 25 {
+		t.Errorf("Read %d pages from %v, next record %d, error %v", pages, fromRecords, pager.fromRecord, pager.err)
+	}
+
+	if pager.next() || len(fromRecords) != 2 {
+		t.Error("A finished pager should not fetch again")
+	}
+
+	pager = recordPager{fetch: func(fromRecord int) (int, bool, error) {
+		return 0, true, nil
+	}}
+
+	if pager.next() || pager.next() || pager.err != nil {
+		t.Error("An empty page should end the pager even when more are available")
+	}
+
+	failure := errors.New("failure")
+	pager = recordPager{fetch: func(fromRecord int) (int, bool, error) {
+		return 0, false, failure
+	}}
+
+	if pager.next() || pager.err != failure {
+		t.Errorf("Expected the fetch error, got %v", pager.err)
+	}
+}
@@ -0,0 +1,656 @@
+package betfair
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Price data fetched to match paper orders
+var paperPriceProjection = PriceProjection{PriceData: []string{"EX_ALL_OFFERS", "EX_TRADED", "SP_AVAILABLE", "SP_TRADED"}}
+
+type paperOrder struct {
+	summary      CurrentOrderSumary
+	limitPrice   float64
+	liability    float64
+	placedInPlay bool
+	// size waiting at our price ahead of the order and the traded volume
+	// at that price when the queue was last measured
+	queueAhead float64
+	traded     float64
+}
+
+func (order *paperOrder) remaining() float64 {
+	return order.summary.SizeRemaining
+}
+
+func (order *paperOrder) fill(price, size float64) {
+	if size > order.summary.SizeRemaining {
+		size = order.summary.SizeRemaining
+	}
+
+	if size <= 0 {
+		return
+	}
+
+	matched := order.summary.SizeMatched
+	order.summary.AveragePriceMatched = (order.summary.AveragePriceMatched*matched + price*size) / (matched + size)
+	order.summary.SizeMatched = matched + size
+	order.summary.SizeRemaining -= size
+	matchedDate := time.Now().UTC()
+	order.summary.MatchedDate = &matchedDate
+
+	if order.summary.SizeRemaining <= 0 {
+		order.summary.SizeRemaining = 0
+		order.summary.Status = OrderStatusExecutionComplete
+	}
+}
+
+func (order *paperOrder) lapse() {
+	order.summary.SizeLapsed += order.summary.SizeRemaining
+	order.summary.SizeRemaining = 0
+	order.summary.Status = OrderStatusExecutionComplete
+}
+
+func (order *paperOrder) cancel(size float64) float64 {
+	if size <= 0 || size > order.summary.SizeRemaining {
+		size = order.summary.SizeRemaining
+	}
+
+	order.summary.SizeCancelled += size
+	order.summary.SizeRemaining -= size
+
+	if order.summary.SizeRemaining <= 0 {
+		order.summary.SizeRemaining = 0
+		order.summary.Status = OrderStatusExecutionComplete
+	}
+
+	return size
+}
+
+// PaperExecutor simulates the order API against live market books without
+// sending any instruction to the exchange. Limit orders match the prices
+// available when placed, rest in a queue behind the size already offered at
+// their price and fill as traded volume at that price grows. On close orders
+// fill at the starting price once the market is reconciled. Call Refresh to
+// advance resting orders between order calls.
+type PaperExecutor struct {
+	api    *API
+	m      sync.Mutex
+	betID  int64
+	orders []*paperOrder
+}
+
+func NewPaperExecutor(api *API) *PaperExecutor {
+	return &PaperExecutor{api: api}
+}
+
+func (executor *PaperExecutor) fetchMarketBook(marketID string) (*MarketBook, error) {
+	books, err := executor.api.ListMarketBook([]string{marketID}, Options{"priceProjection": paperPriceProjection})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(books) == 0 {
+		return nil, fmt.Errorf("Market `%s` not found", marketID)
+	}
+
+	// matching consumes the available sizes, keep them off the caller's data
+	book := books[0]
+
+	for i, runner := range book.Runners {
+		if runner.EX != nil {
+			ex := *runner.EX
+			ex.AvailableToBack = append([]PriceSize(nil), ex.AvailableToBack...)
+			ex.AvailableToLay = append([]PriceSize(nil), ex.AvailableToLay...)
+			book.Runners[i].EX = &ex
+		}
+	}
+
+	return &book, nil
+}
+
+func findRunner(book *MarketBook, selectionID int64, handicap float64) *Runner {
+	for i := range book.Runners {
+		if book.Runners[i].SelectionID == selectionID && book.Runners[i].Handicap == handicap {
+			return &book.Runners[i]
+		}
+	}
+
+	return nil
+}
+
+func sizeAtPrice(levels []PriceSize, price float64) float64 {
+	for _, level := range levels {
+		if toHundredths(level.Price) == toHundredths(price) {
+			return level.Size
+		}
+	}
+
+	return 0
+}
+
+// crossingLevels returns the prices an order on side can be matched at, best
+// price first
+func crossingLevels(runner *Runner, side Side) []PriceSize {
+	if runner.EX == nil {
+		return nil
+	}
+
+	if side == SideBack {
+		return runner.EX.AvailableToBack
+	}
+
+	return runner.EX.AvailableToLay
+}
+
+// restingLevels returns the prices an order on side waits at
+func restingLevels(runner *Runner, side Side) []PriceSize {
+	if runner.EX == nil {
+		return nil
+	}
+
+	if side == SideBack {
+		return runner.EX.AvailableToLay
+	}
+
+	return runner.EX.AvailableToBack
+}
+
+func crosses(side Side, levelPrice, price float64) bool {
+	if side == SideBack {
+		return levelPrice >= price
+	}
+
+	return levelPrice <= price
+}
+
+func crossingSize(runner *Runner, side Side, price float64) (size float64) {
+	for _, level := range crossingLevels(runner, side) {
+		if crosses(side, level.Price, price) {
+			size += level.Size
+		}
+	}
+
+	return size
+}
+
+// matchCrossing fills the order against crossing prices and consumes the
+// matched size from the book
+func matchCrossing(order *paperOrder, runner *Runner) {
+	levels := crossingLevels(runner, order.summary.Side)
+
+	for i := range levels {
+		if order.remaining() <= 0 || !crosses(order.summary.Side, levels[i].Price, order.limitPrice) {
+			continue
+		}
+
+		size := levels[i].Size
+
+		if size > order.remaining() {
+			size = order.remaining()
+		}
+
+		order.fill(levels[i].Price, size)
+		levels[i].Size -= size
+	}
+}
+
+func (executor *PaperExecutor) nextBetID() string {
+	executor.betID++
+	return fmt.Sprintf("paper-%d", executor.betID)
+}
+
+func (executor *PaperExecutor) findOrder(betID string) *paperOrder {
+	for _, order := range executor.orders {
+		if order.summary.BetID == betID {
+			return order
+		}
+	}
+
+	return nil
+}
+
+// settle advances the resting orders of the book's market
+func (executor *PaperExecutor) settle(book *MarketBook) {
+	for _, order := range executor.orders {
+		if order.summary.MarketID != book.MarketID || order.remaining() <= 0 {
+			continue
+		}
+
+		runner := findRunner(book, order.summary.SelectionID, order.summary.Handicap)
+
+		if runner == nil || runner.Status == RunnerStatusRemoved {
+			order.lapse()
+			continue
+		}
+
+		if order.summary.OrderType != OrderTypeLimit {
+			settleOnClose(order, book, runner)
+			continue
+		}
+
+		if book.Status == MarketStatusOpen {
+			matchCrossing(order, runner)
+			matchQueue(order, runner)
+		}
+
+		if order.remaining() <= 0 {
+			continue
+		}
+
+		persistenceType := order.summary.PersistenceType
+
+		if persistenceType == PersistenceTypeMarketOnClose && book.BspReconciled && runner.SP != nil && runner.SP.ActualSP > 0 {
+			order.fill(runner.SP.ActualSP, order.remaining())
+		} else if book.Status == MarketStatusClosed || (persistenceType == PersistenceTypeLapse && book.Inplay && !order.placedInPlay) {
+			order.lapse()
+		}
+	}
+}
+
+// matchQueue fills a resting order with the volume traded at its price
+// once the size queued ahead of it has been consumed
+func matchQueue(order *paperOrder, runner *Runner) {
+	if order.remaining() <= 0 || runner.EX == nil {
+		return
+	}
+
+	traded := sizeAtPrice(runner.EX.TradedVolume, order.limitPrice)
+	volume := traded - order.traded
+	order.traded = traded
+
+	if volume > 0 {
+		consumed := volume
+
+		if consumed > order.queueAhead {
+			consumed = order.queueAhead
+		}
+
+		order.queueAhead -= consumed
+		order.fill(order.limitPrice, volume-consumed)
+	}
+
+	// cancellations ahead of the order move it up the queue
+	if visible := sizeAtPrice(restingLevels(runner, order.summary.Side), order.limitPrice); visible < order.queueAhead {
+		order.queueAhead = visible
+	}
+}
+
+func settleOnClose(order *paperOrder, book *MarketBook, runner *Runner) {
+	if !book.BspReconciled || runner.SP == nil || runner.SP.ActualSP <= 0 {
+		if book.Status == MarketStatusClosed {
+			order.lapse()
+		}
+
+		return
+	}
+
+	sp := runner.SP.ActualSP
+
+	if order.summary.OrderType == OrderTypeLimitOnClose && !crosses(order.summary.Side, sp, order.limitPrice) {
+		order.lapse()
+		return
+	}
+
+	// lay liabilities are converted into stakes at the starting price
+	if order.summary.Side == SideLay && sp > 1 {
+		order.summary.SizeRemaining = order.liability / (sp - 1)
+	}
+
+	order.fill(sp, order.remaining())
+}
+
+func (executor *PaperExecutor) place(book *MarketBook, instruction PlaceInstruction) PlaceInstructionReport {
+	var report = PlaceInstructionReport{Instruction: instruction}
+
+	if book.Status != MarketStatusOpen {
+		report.Status = "FAILURE"
+		report.ErrorCode = "MARKET_NOT_OPEN_FOR_BETTING"
+		return report
+	}
+
+	runner := findRunner(book, instruction.SelectionID, instruction.Handicap)
+
+	if runner == nil {
+		report.Status = "FAILURE"
+		report.ErrorCode = "INVALID_RUNNER"
+		return report
+	}
+
+	if runner.Status == RunnerStatusRemoved {
+		report.Status = "FAILURE"
+		report.ErrorCode = "RUNNER_REMOVED"
+		return report
+	}
+
+	now := time.Now().UTC()
+	order := &paperOrder{
+		summary: CurrentOrderSumary{
+			BetID:            executor.nextBetID(),
+			MarketID:         book.MarketID,
+			SelectionID:      instruction.SelectionID,
+			Handicap:         instruction.Handicap,
+			Side:             instruction.Side,
+			Status:           OrderStatusExecutable,
+			OrderType:        instruction.OrderType,
+			PlacedDate:       now,
+			CustomerOrderRef: instruction.CustomerOrderRef,
+		},
+		placedInPlay: book.Inplay,
+	}
+
+	switch instruction.OrderType {
+	case OrderTypeLimit:
+		limitOrder := instruction.LimitOrder
+		size := limitOrder.Size
+
+		switch limitOrder.BetTargetType {
+		case "PAYOUT":
+			size = limitOrder.BetTargetSize / limitOrder.Price
+		case "BACKERS_PROFIT":
+			size = limitOrder.BetTargetSize / (limitOrder.Price - 1)
+		}
+
+		order.limitPrice = limitOrder.Price
+		order.summary.PriceSize = PriceSize{Price: limitOrder.Price, Size: size}
+		order.summary.PersistenceType = limitOrder.PersistenceType
+		order.summary.SizeRemaining = size
+
+		if limitOrder.TimeInForce == "FILL_OR_KILL" {
+			required := limitOrder.MinFillSize
+
+			if required <= 0 {
+				required = size
+			}
+
+			if crossingSize(runner, instruction.Side, limitOrder.Price) >= required {
+				matchCrossing(order, runner)
+			}
+
+			order.lapse()
+			break
+		}
+
+		matchCrossing(order, runner)
+		order.queueAhead = sizeAtPrice(restingLevels(runner, instruction.Side), limitOrder.Price)
+
+		if runner.EX != nil {
+			order.traded = sizeAtPrice(runner.EX.TradedVolume, limitOrder.Price)
+		}
+	case OrderTypeLimitOnClose:
+		order.limitPrice = instruction.LimitOnCloseOrder.Price
+		order.liability = instruction.LimitOnCloseOrder.Liability
+		order.summary.PriceSize = PriceSize{Price: order.limitPrice, Size: order.liability}
+		order.summary.BSPLiability = order.liability
+		order.summary.SizeRemaining = order.liability
+	case OrderTypeMarketOnClose:
+		order.liability = instruction.MarketOnCloseOrder.Liability
+		order.summary.PriceSize = PriceSize{Size: order.liability}
+		order.summary.BSPLiability = order.liability
+		order.summary.SizeRemaining = order.liability
+	}
+
+	executor.orders = append(executor.orders, order)
+
+	report.Status = "SUCCESS"
+	report.OrderStatus = order.summary.Status
+	report.BetID = order.summary.BetID
+	report.PlacedDate = now
+	report.AveragePriceMatched = order.summary.AveragePriceMatched
+	report.SizeMatched = order.summary.SizeMatched
+	return report
+}
+
+func (executor *PaperExecutor) cancel(instruction CancelInstruction, marketID string) CancelInstructionReport {
+	var report = CancelInstructionReport{Instruction: instruction}
+	order := executor.findOrder(instruction.BetID)
+
+	if order == nil || (marketID != "" && order.summary.MarketID != marketID) || order.remaining() <= 0 {
+		report.Status = "FAILURE"
+		report.ErrorCode = "BET_TAKEN_OR_LAPSED"
+		return report
+	}
+
+	report.Status = "SUCCESS"
+	report.SizeCancelled = order.cancel(instruction.SizeReduction)
+	report.CancelledDate = time.Now().UTC()
+	return report
+}
+
+// Refresh fetches the market book and advances the resting orders of the market
+func (executor *PaperExecutor) Refresh(marketID string) error {
+	book, err := executor.fetchMarketBook(marketID)
+
+	if err != nil {
+		return err
+	}
+
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	executor.settle(book)
+	return nil
+}
+
+// CurrentOrders returns the paper orders of the market, or of every market
+// when marketID is blank
+func (executor *PaperExecutor) CurrentOrders(marketID string) []CurrentOrderSumary {
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	var orders = []CurrentOrderSumary{}
+
+	for _, order := range executor.orders {
+		if marketID == "" || order.summary.MarketID == marketID {
+			orders = append(orders, order.summary)
+		}
+	}
+
+	return orders
+}
+
+func (executor *PaperExecutor) PlaceOrders(marketID string, instructions []PlaceInstruction, options Options) (result PlaceExecutionReport, err error) {
+	ladder, _, err := priceLadderOption(options)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = validatePlaceInstructions(instructions, executor.api.session.account.Currency, ladder)
+
+	if err != nil {
+		return result, err
+	}
+
+	book, err := executor.fetchMarketBook(marketID)
+
+	if err != nil {
+		return result, err
+	}
+
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	executor.settle(book)
+
+	result.CustomerRef, _ = options["customerRef"].(string)
+	result.MarketID = marketID
+
+	for _, instruction := range instructions {
+		report := executor.place(book, instruction)
+		result.Status = mergeExecutionStatus(result.Status, report.Status)
+		result.ErrorCode = mergeErrorCode(result.ErrorCode, report.ErrorCode)
+		result.InstructionReports = append(result.InstructionReports, report)
+	}
+
+	return result, nil
+}
+
+func (executor *PaperExecutor) CancelOrders(marketID string, instructions []CancelInstruction, options Options) (result CancelExecutionReport, err error) {
+	err = validateCancelInstructions(marketID, instructions)
+
+	if err != nil {
+		return result, err
+	}
+
+	var marketIDs []string
+
+	if marketID != "" {
+		marketIDs = append(marketIDs, marketID)
+	} else {
+		for _, order := range executor.CurrentOrders("") {
+			if order.Status == OrderStatusExecutable && !containsString(marketIDs, order.MarketID) {
+				marketIDs = append(marketIDs, order.MarketID)
+			}
+		}
+	}
+
+	for _, id := range marketIDs {
+		if err = executor.Refresh(id); err != nil {
+			return result, err
+		}
+	}
+
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	result.CustomerRef, _ = options["customerRef"].(string)
+	result.MarketID = marketID
+	result.Status = "SUCCESS"
+
+	if len(instructions) == 0 {
+		for _, order := range executor.orders {
+			if order.remaining() > 0 && (marketID == "" || order.summary.MarketID == marketID) {
+				order.cancel(0)
+			}
+		}
+
+		return result, nil
+	}
+
+	result.Status = ""
+
+	for _, instruction := range instructions {
+		report := executor.cancel(instruction, marketID)
+		result.Status = mergeExecutionStatus(result.Status, report.Status)
+		result.ErrorCode = mergeErrorCode(result.ErrorCode, report.ErrorCode)
+		result.InstructionReports = append(result.InstructionReports, report)
+	}
+
+	return result, nil
+}
+
+func (executor *PaperExecutor) UpdateOrders(marketID string, instructions []UpdateInstruction, options Options) (result UpdateExecutionReport, err error) {
+	err = validateUpdateInstructions(instructions)
+
+	if err != nil {
+		return result, err
+	}
+
+	if err = executor.Refresh(marketID); err != nil {
+		return result, err
+	}
+
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	result.CustomerRef, _ = options["customerRef"].(string)
+	result.MarketID = marketID
+
+	for _, instruction := range instructions {
+		var report = UpdateInstructionReport{Status: "SUCCESS", Instruction: instruction}
+		order := executor.findOrder(instruction.BetID)
+
+		if order == nil || order.summary.MarketID != marketID || order.remaining() <= 0 || order.summary.OrderType != OrderTypeLimit {
+			report.Status = "FAILURE"
+			report.ErrorCode = "BET_TAKEN_OR_LAPSED"
+		} else {
+			order.summary.PersistenceType = instruction.NewPersistenceType
+		}
+
+		result.Status = mergeExecutionStatus(result.Status, report.Status)
+		result.ErrorCode = mergeErrorCode(result.ErrorCode, report.ErrorCode)
+		result.InstructionReports = append(result.InstructionReports, report)
+	}
+
+	return result, nil
+}
+
+func (executor *PaperExecutor) ReplaceOrders(marketID string, instructions []ReplaceInstruction, options Options) (result ReplaceExecutionReport, err error) {
+	ladder, _, err := priceLadderOption(options)
+
+	if err != nil {
+		return result, err
+	}
+
+	err = validateReplaceInstructions(instructions, ladder)
+
+	if err != nil {
+		return result, err
+	}
+
+	book, err := executor.fetchMarketBook(marketID)
+
+	if err != nil {
+		return result, err
+	}
+
+	executor.m.Lock()
+	defer executor.m.Unlock()
+
+	executor.settle(book)
+
+	result.CustomerRef, _ = options["customerRef"].(string)
+	result.MarketID = marketID
+
+	for _, instruction := range instructions {
+		var report = ReplaceInstructionReport{Status: "SUCCESS"}
+		order := executor.findOrder(instruction.BetID)
+
+		if order != nil && order.summary.OrderType != OrderTypeLimit {
+			order = nil
+		}
+
+		cancelReport := executor.cancel(CancelInstruction{BetID: instruction.BetID}, marketID)
+		report.CancelInstructionReport = &cancelReport
+
+		if cancelReport.Status != "SUCCESS" || order == nil {
+			report.Status = "FAILURE"
+			report.ErrorCode = mergeErrorCode("", cancelReport.ErrorCode)
+		} else {
+			placeReport := executor.place(book, PlaceInstruction{
+				OrderType:   OrderTypeLimit,
+				SelectionID: order.summary.SelectionID,
+				Handicap:    order.summary.Handicap,
+				Side:        order.summary.Side,
+				LimitOrder: &LimitOrder{
+					Size:            cancelReport.SizeCancelled,
+					Price:           instruction.NewPrice,
+					PersistenceType: order.summary.PersistenceType,
+				},
+			})
+
+			report.PlaceInstructionReport = &placeReport
+			report.Status = placeReport.Status
+			report.ErrorCode = placeReport.ErrorCode
+		}
+
+		result.Status = mergeExecutionStatus(result.Status, report.Status)
+		result.ErrorCode = mergeErrorCode(result.ErrorCode, report.ErrorCode)
+		result.InstructionReports = append(result.InstructionReports, report)
+	}
+
+	return result, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package betfair
+
+import (
+	"math"
+	"testing"
+)
+
+var _ OrderExecutor = (*API)(nil)
+var _ OrderExecutor = (*PaperExecutor)(nil)
+
+func getTestPaperExecutor(t *testing.T, book *MarketBook) *PaperExecutor {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if call.Method != listMarketBook {
+			t.Errorf("Unexpected method %s", call.Method)
+		}
+
+		return []MarketBook{*book}
+	})
+
+	return NewPaperExecutor(api)
+}
+
+func getTestPaperBook() *MarketBook {
+	return &MarketBook{
+		MarketID: "1.114363660",
+		Status:   "OPEN",
+		Runners: []Runner{
+			{
+				SelectionID: 47972,
+				Status:      "ACTIVE",
+				EX: &ExchangePrices{
+					AvailableToBack: []PriceSize{{Price: 3, Size: 10}, {Price: 2.9, Size: 5}},
+					AvailableToLay:  []PriceSize{{Price: 3.1, Size: 8}, {Price: 3.2, Size: 20}},
+					TradedVolume:    []PriceSize{{Price: 3, Size: 50}, {Price: 3.1, Size: 100}},
+				},
+			},
+		},
+	}
+}
+
+func TestPaperPlaceMatchesAvailablePrices(t *testing.T) {
+	executor := getTestPaperExecutor(t, getTestPaperBook())
+
+	report, err := executor.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(2.9, 15)}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	instructionReport := report.InstructionReports[0]
+
+	if report.Status != "SUCCESS" || instructionReport.OrderStatus != "EXECUTION_COMPLETE" || instructionReport.SizeMatched != 15 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	if math.Abs(instructionReport.AveragePriceMatched-(3*10+2.9*5)/15) > 1e-9 {
+		t.Errorf("Unexpected average price %v", instructionReport.AveragePriceMatched)
+	}
+}
+
+func TestPaperQueuePosition(t *testing.T) {
+	book := getTestPaperBook()
+	executor := getTestPaperExecutor(t, book)
+
+	report, err := executor.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(3.1, 10)}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	betID := report.InstructionReports[0].BetID
+
+	if report.InstructionReports[0].OrderStatus != "EXECUTABLE" || report.InstructionReports[0].SizeMatched != 0 {
+		t.Errorf("Order should rest unmatched, got %+v", report.InstructionReports[0])
+	}
+
+	// 8 queued ahead of the order, the remaining 4 of the traded volume fill it
+	book.Runners[0].EX.TradedVolume[1].Size = 112
+
+	if err := executor.Refresh("1.114363660"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	orders := executor.CurrentOrders("1.114363660")
+
+	if len(orders) != 1 || orders[0].SizeMatched != 4 || orders[0].SizeRemaining != 6 {
+		t.Errorf("Unexpected orders %+v", orders)
+		return
+	}
+
+	cancelReport, err := executor.CancelOrders("1.114363660", []CancelInstruction{{BetID: betID}}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if cancelReport.Status != "SUCCESS" || cancelReport.InstructionReports[0].SizeCancelled != 6 {
+		t.Errorf("Unexpected cancel report %+v", cancelReport)
+	}
+
+	if orders := executor.CurrentOrders(""); orders[0].Status != "EXECUTION_COMPLETE" {
+		t.Errorf("Cancelled order should be complete, got %+v", orders[0])
+	}
+}
+
+func TestPaperReplaceAndLapse(t *testing.T) {
+	book := getTestPaperBook()
+	executor := getTestPaperExecutor(t, book)
+
+	instruction := getTestLimitInstruction(3.5, 4)
+	instruction.Side = SideLay
+
+	report, err := executor.PlaceOrders("1.114363660", []PlaceInstruction{instruction}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if report.InstructionReports[0].SizeMatched != 4 || report.InstructionReports[0].AveragePriceMatched != 3.1 {
+		t.Errorf("Lay should match the best lay price, got %+v", report.InstructionReports[0])
+	}
+
+	report, err = executor.PlaceOrders("1.114363660", []PlaceInstruction{getTestLimitInstruction(4, 5)}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	replaceReport, err := executor.ReplaceOrders("1.114363660", []ReplaceInstruction{{BetID: report.InstructionReports[0].BetID, NewPrice: 3.5}}, Options{})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	newBetID, ok := replaceReport.BetIDs()[report.InstructionReports[0].BetID]
+
+	if !ok || replaceReport.InstructionReports[0].PlaceInstructionReport.Instruction.LimitOrder.Size != 5 {
+		t.Errorf("Unexpected replace report %+v", replaceReport)
+		return
+	}
+
+	book.Inplay = true
+
+	if err := executor.Refresh("1.114363660"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, order := range executor.CurrentOrders("") {
+		if order.BetID == newBetID && (order.SizeLapsed != 5 || order.Status != "EXECUTION_COMPLETE") {
+			t.Errorf("LAPSE order should lapse in play, got %+v", order)
+		}
+	}
+}
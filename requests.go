@@ -0,0 +1,200 @@
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Typed parameters of the listing methods. Exchange picks the endpoint from
+// BettingApiEndpoints and is not sent, blank means the default exchange.
+
+type ListEventTypesRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListCompetitionsRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListEventsRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListCountriesRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListVenuesRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListMarketTypesRequest struct {
+	Filter   MarketFilter `json:"filter"`
+	Locale   string       `json:"locale,omitempty"`
+	Exchange string       `json:"-"`
+}
+
+type ListTimeRangesRequest struct {
+	Filter      MarketFilter    `json:"filter"`
+	Granularity TimeGranularity `json:"granularity"`
+	Exchange    string          `json:"-"`
+}
+
+type ListMarketCatalogueRequest struct {
+	Filter           MarketFilter `json:"filter"`
+	MarketProjection []string     `json:"marketProjection,omitempty"`
+	Sort             string       `json:"sort,omitempty"`
+	MaxResults       int          `json:"maxResults"`
+	Locale           string       `json:"locale,omitempty"`
+	Exchange         string       `json:"-"`
+}
+
+type ListMarketBookRequest struct {
+	MarketIDs                     []string         `json:"marketIds"`
+	PriceProjection               *PriceProjection `json:"priceProjection,omitempty"`
+	OrderProjection               OrderProjection  `json:"orderProjection,omitempty"`
+	MatchProjection               string           `json:"matchProjection,omitempty"`
+	IncludeOverallPosition        *bool            `json:"includeOverallPosition,omitempty"`
+	PartitionMatchedByStrategyRef bool             `json:"partitionMatchedByStrategyRef,omitempty"`
+	CustomerStrategyRefs          []string         `json:"customerStrategyRefs,omitempty"`
+	CurrencyCode                  string           `json:"currencyCode,omitempty"`
+	Locale                        string           `json:"locale,omitempty"`
+	MatchedSince                  *time.Time       `json:"matchedSince,omitempty"`
+	BetIDs                        []string         `json:"betIds,omitempty"`
+	Exchange                      string           `json:"-"`
+}
+
+type ListRunnerBookRequest struct {
+	MarketID                      string           `json:"marketId"`
+	SelectionID                   int64            `json:"selectionId"`
+	Handicap                      *float64         `json:"handicap,omitempty"`
+	PriceProjection               *PriceProjection `json:"priceProjection,omitempty"`
+	OrderProjection               OrderProjection  `json:"orderProjection,omitempty"`
+	MatchProjection               string           `json:"matchProjection,omitempty"`
+	IncludeOverallPosition        *bool            `json:"includeOverallPosition,omitempty"`
+	PartitionMatchedByStrategyRef bool             `json:"partitionMatchedByStrategyRef,omitempty"`
+	CustomerStrategyRefs          []string         `json:"customerStrategyRefs,omitempty"`
+	CurrencyCode                  string           `json:"currencyCode,omitempty"`
+	Locale                        string           `json:"locale,omitempty"`
+	MatchedSince                  *time.Time       `json:"matchedSince,omitempty"`
+	BetIDs                        []string         `json:"betIds,omitempty"`
+	Exchange                      string           `json:"-"`
+}
+
+type ListMarketProfitAndLossRequest struct {
+	MarketIDs          []string `json:"marketIds"`
+	IncludeSettledBets bool     `json:"includeSettledBets,omitempty"`
+	IncludeBspBets     bool     `json:"includeBspBets,omitempty"`
+	NetOfCommission    bool     `json:"netOfCommission,omitempty"`
+	Exchange           string   `json:"-"`
+}
+
+type ListCurrentOrdersRequest struct {
+	BetIDs                 []string        `json:"betIds,omitempty"`
+	MarketIDs              []string        `json:"marketIds,omitempty"`
+	OrderProjection        OrderProjection `json:"orderProjection,omitempty"`
+	CustomerOrderRefs      []string        `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs   []string        `json:"customerStrategyRefs,omitempty"`
+	DateRange              *TimeRange      `json:"dateRange,omitempty"`
+	OrderBy                OrderBy         `json:"orderBy,omitempty"`
+	SortDir                SortDir         `json:"sortDir,omitempty"`
+	FromRecord             int             `json:"fromRecord,omitempty"`
+	RecordCount            int             `json:"recordCount,omitempty"`
+	IncludeItemDescription bool            `json:"includeItemDescription,omitempty"`
+	Exchange               string          `json:"-"`
+}
+
+type ListClearedOrdersRequest struct {
+	BetStatus              BetStatus  `json:"betStatus"`
+	EventTypeIDs           []string   `json:"eventTypeIds,omitempty"`
+	EventIDs               []string   `json:"eventIds,omitempty"`
+	MarketIDs              []string   `json:"marketIds,omitempty"`
+	RunnerIDs              []RunnerID `json:"runnerIds,omitempty"`
+	BetIDs                 []string   `json:"betIds,omitempty"`
+	CustomerOrderRefs      []string   `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs   []string   `json:"customerStrategyRefs,omitempty"`
+	Side                   Side       `json:"side,omitempty"`
+	SettledDateRange       *TimeRange `json:"settledDateRange,omitempty"`
+	GroupBy                GroupBy    `json:"groupBy,omitempty"`
+	IncludeItemDescription bool       `json:"includeItemDescription,omitempty"`
+	Locale                 string     `json:"locale,omitempty"`
+	FromRecord             int        `json:"fromRecord,omitempty"`
+	RecordCount            int        `json:"recordCount,omitempty"`
+	Exchange               string     `json:"-"`
+}
+
+// requestFields returns the JSON names of the fields of a request struct
+func requestFields(request interface{}) map[string]bool {
+	var fields = map[string]bool{}
+	requestType := reflect.TypeOf(request)
+
+	for requestType.Kind() == reflect.Ptr {
+		requestType = requestType.Elem()
+	}
+
+	for i := 0; i < requestType.NumField(); i++ {
+		name := strings.Split(requestType.Field(i).Tag.Get("json"), ",")[0]
+
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
+
+// requestFromOptions decodes options into a typed request and returns the
+// exchange they select. Keys without a request field are rejected, except
+// for the default options that not every request takes. Values must have
+// the JSON type of their field, "10" does not decode into an int.
+func requestFromOptions(options Options, request interface{}) (string, error) {
+	fields := requestFields(request)
+
+	var keys []string
+
+	for key := range options {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := defaultOptions[key]; !ok && !fields[key] {
+			return "", fmt.Errorf("Unknown option `%s`", key)
+		}
+	}
+
+	body, err := json.Marshal(options)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(body, request)
+
+	if err != nil {
+		return "", err
+	}
+
+	exchange, ok := options["exchange"]
+
+	if !ok {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%v", exchange), nil
+}
@@ -0,0 +1,88 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOptionsWrapperSendsTypedRequest(t *testing.T) {
+	var params map[string]json.RawMessage
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		params = nil
+
+		if err := json.Unmarshal(call.Params, &params); err != nil {
+			t.Error(err)
+		}
+
+		if call.Method == listCurrentOrders {
+			return CurrentOrderSummaryReport{}
+		}
+
+		return []MarketCatalogue{}
+	})
+
+	_, err := api.ListMarketCatalogue(Options{"maxResults": 5, "exchange": "UK"})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(params["maxResults"]) != "5" || string(params["locale"]) != `"en"` {
+		t.Errorf("Unexpected params %v", params)
+	}
+
+	if string(params["marketProjection"]) != `["EVENT","EVENT_TYPE","COMPETITION"]` {
+		t.Errorf("Default market projection was not sent, got %s", params["marketProjection"])
+	}
+
+	if _, ok := params["exchange"]; ok {
+		t.Error("Exchange should not be sent")
+	}
+
+	params = nil
+	_, err = api.ListMarketCatalogue(Options{"maxResults": 5, "marketProjecton": []string{"RUNNER_METADATA"}})
+
+	if err == nil || params != nil {
+		t.Errorf("Unknown options should be rejected before sending, got %v", err)
+	}
+
+	_, err = api.ListCurrentOrders(Options{"exchange": "uk", "locale": "en"})
+
+	if err != nil {
+		t.Errorf("Default options should be accepted, got %v", err)
+	}
+}
+
+func TestTypedMarketBookRequest(t *testing.T) {
+	var request ListMarketBookRequest
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		if err := json.Unmarshal(call.Params, &request); err != nil {
+			t.Error(err)
+		}
+
+		return []MarketBook{{MarketID: "1.114363660"}}
+	})
+
+	books, err := api.ListMarketBookWith(ListMarketBookRequest{
+		MarketIDs:       []string{"1.114363660"},
+		PriceProjection: &PriceProjection{PriceData: []string{"EX_BEST_OFFERS"}},
+		CurrencyCode:    "EUR",
+		BetIDs:          []string{"31242604945"},
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(books) != 1 || request.CurrencyCode != "EUR" || request.PriceProjection.PriceData[0] != "EX_BEST_OFFERS" || request.BetIDs[0] != "31242604945" {
+		t.Errorf("Unexpected request %+v", request)
+	}
+
+	if _, err := api.ListMarketBookWith(ListMarketBookRequest{Exchange: "mars"}); err == nil {
+		t.Error("Unknown exchange should be rejected")
+	}
+}
@@ -41,12 +41,12 @@ func (client *pooledHTTPClient) Do(req *http.Request) ([]byte, error) {
 
 	res, err := client.Client.Do(req)
 
-	defer res.Body.Close()
-
 	if err != nil {
 		return nil, err
 	}
 
+	defer res.Body.Close()
+
 	return ioutil.ReadAll(res.Body)
 }
 
@@ -75,6 +75,7 @@ func initializeHTTPClient(account *Account) (*pooledHTTPClient, error) {
 		ssl.Rand = rand.Reader
 
 		var httpClient = &http.Client{
+			Timeout: ClientTimeout,
 			Transport: &http.Transport{
 				Dial: func(network, addr string) (net.Conn, error) {
 					return net.DialTimeout(network, addr, time.Duration(ClientTimeout))
@@ -87,6 +88,7 @@ func initializeHTTPClient(account *Account) (*pooledHTTPClient, error) {
 	}
 
 	var httpClient = &http.Client{
+		Timeout: ClientTimeout,
 		Transport: &http.Transport{
 			Dial: func(network, addr string) (net.Conn, error) {
 				return net.DialTimeout(network, addr, time.Duration(ClientTimeout))
@@ -102,6 +104,7 @@ type Session struct {
 	account    *Account
 	httpClient *pooledHTTPClient
 	m          sync.Mutex
+	keepAlive  bool
 }
 
 func NewSession(account *Account) (*Session, error) {
@@ -128,7 +131,8 @@ func (session *Session) GetToken() (string, error) {
 
 			session.ssoid = ssoid
 
-			if session.account.KeepAlive {
+			if session.account.KeepAlive && !session.keepAlive {
+				session.keepAlive = true
 				go session.startKeepAliveLoop()
 			}
 		}
@@ -137,6 +141,17 @@ func (session *Session) GetToken() (string, error) {
 	return session.ssoid, nil
 }
 
+// resetToken drops an expired token so the next GetToken logs in again,
+// unless the token was already replaced
+func (session *Session) resetToken(token string) {
+	session.m.Lock()
+	defer session.m.Unlock()
+
+	if session.ssoid == token {
+		session.ssoid = ""
+	}
+}
+
 var keepAliveReader = strings.NewReader("")
 
 func (session *Session) KeepAlive() (bool, error) {
@@ -0,0 +1,872 @@
+package betfair
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Exchange Stream API endpoint
+var StreamEndpoint = "stream-api.betfair.com:443"
+
+// Timeout of the stream dial and of requests waiting for their status
+var StreamTimeout = time.Second * 10
+
+// Messages buffered for the reader of Stream.Messages. While the buffer is
+// full further messages are dropped and counted by Stream.Dropped, the
+// caches are updated regardless. ReconnectEvent and CacheResetEvent values
+// are never dropped, queued messages are dropped to make room for them.
+var StreamMessageBuffer = 1000
+
+// Reconnect attempts after the connection drops, zero disables reconnecting
+var StreamReconnectAttempts = 10
+
+// Delay before the second reconnect attempt, doubled for every further
+// attempt up to StreamMaxReconnectDelay. The first attempt is immediate.
+var StreamReconnectDelay = time.Second
+
+var StreamMaxReconnectDelay = time.Second * 30
+
+// Heartbeats a subscription may miss before the stream reconnects
+var StreamMissedHeartbeats = 3
+
+// Heartbeat interval of subscriptions that do not set HeartbeatMs
+const defaultStreamHeartbeatMs = 5000
+
+// Status of change messages sent while the exchange's data is stale
+const streamStatusStale = 503
+
+// dialStream opens the connection to the stream endpoint, tests replace it
+var dialStream = func(endpoint string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.DialWithDialer(&net.Dialer{Timeout: StreamTimeout}, "tcp", endpoint, &tls.Config{ServerName: host})
+}
+
+const streamStatusFailure = "FAILURE"
+
+// ErrStreamClosed is returned by requests on a closed stream
+var ErrStreamClosed = errors.New("Stream closed")
+
+// ErrStreamNotConnected is returned by requests made before Connect
+var ErrStreamNotConnected = errors.New("Stream not connected")
+
+// ErrMissedHeartbeat drops a connection that stayed silent for
+// StreamMissedHeartbeats heartbeat intervals
+var ErrMissedHeartbeat = errors.New("Stream missed heartbeats")
+
+// ErrStreamDisconnected is returned by requests whose connection dropped
+// before their status arrived
+var ErrStreamDisconnected = errors.New("Stream disconnected")
+
+// Error codes of failures a reconnect cannot fix
+var streamFatalErrorCodes = map[string]bool{
+	"NO_APP_KEY":                    true,
+	"INVALID_APP_KEY":               true,
+	"NOT_AUTHORIZED":                true,
+	"INVALID_INPUT":                 true,
+	"INVALID_REQUEST":               true,
+	"SUBSCRIPTION_LIMIT_EXCEEDED":   true,
+	"MAX_CONNECTION_LIMIT_EXCEEDED": true,
+}
+
+// Error codes of an expired or unknown session token
+var streamSessionErrorCodes = map[string]bool{
+	"NO_SESSION":                  true,
+	"INVALID_SESSION_INFORMATION": true,
+}
+
+// ConnectionMessage is sent by the exchange when the connection opens
+type ConnectionMessage struct {
+	Op           string `json:"op"`
+	ConnectionID string `json:"connectionId"`
+}
+
+// StatusMessage answers every request, ID matches the request's id
+type StatusMessage struct {
+	Op                   string `json:"op"`
+	ID                   int64  `json:"id"`
+	StatusCode           string `json:"statusCode"`
+	ErrorCode            string `json:"errorCode"`
+	ErrorMessage         string `json:"errorMessage"`
+	ConnectionClosed     bool   `json:"connectionClosed"`
+	ConnectionID         string `json:"connectionId"`
+	ConnectionsAvailable int    `json:"connectionsAvailable"`
+}
+
+// HeartbeatMessage keeps an idle connection open, the exchange answers with
+// a StatusMessage
+type HeartbeatMessage struct {
+	Op string `json:"op"`
+	ID int64  `json:"id"`
+}
+
+type authenticationMessage struct {
+	Op      string `json:"op"`
+	ID      int64  `json:"id"`
+	AppKey  string `json:"appKey"`
+	Session string `json:"session"`
+}
+
+// StreamStatusError is a FAILURE status returned for a request
+type StreamStatusError struct {
+	ErrorCode        string
+	ErrorMessage     string
+	ConnectionClosed bool
+}
+
+func (err *StreamStatusError) Error() string {
+	return fmt.Sprintf("Stream request failed with `%s`: %s", err.ErrorCode, err.ErrorMessage)
+}
+
+// ReconnectEvent is emitted after the stream reconnected and resubscribed,
+// Cause is the error that dropped the previous connection
+type ReconnectEvent struct {
+	ConnectionID string
+	Attempts     int
+	Cause        error
+}
+
+// CacheResetEvent is emitted when the exchange answered a resubscription
+// with a full image instead of the missed deltas. The subscription's cache
+// was cleared and is rebuilt from the image that follows.
+type CacheResetEvent struct {
+	Op             string
+	SubscriptionID int64
+}
+
+func isStreamErrorCode(err error, codes map[string]bool) bool {
+	statusErr, ok := err.(*StreamStatusError)
+	return ok && codes[statusErr.ErrorCode]
+}
+
+func (status StatusMessage) err() error {
+	if status.StatusCode == streamStatusFailure {
+		return &StreamStatusError{ErrorCode: status.ErrorCode, ErrorMessage: status.ErrorMessage, ConnectionClosed: status.ConnectionClosed}
+	}
+
+	return nil
+}
+
+// streamSubscription tracks the clocks a subscription resumes from
+type streamSubscription struct {
+	id         int64
+	initialClk string
+	clk        string
+	imaged     bool
+	heartbeat  time.Duration
+	stale      bool
+}
+
+func newStreamSubscription(id, heartbeatMs int64) streamSubscription {
+	if heartbeatMs <= 0 {
+		heartbeatMs = defaultStreamHeartbeatMs
+	}
+
+	return streamSubscription{id: id, heartbeat: time.Duration(heartbeatMs) * time.Millisecond}
+}
+
+func (subscription *streamSubscription) resetClocks() {
+	subscription.initialClk = ""
+	subscription.clk = ""
+}
+
+// fullImage reports whether a change message starts a full image that
+// replaces an earlier one, the cache must then be cleared first
+func (subscription *streamSubscription) fullImage(changeType, segmentType string) bool {
+	if changeType != ChangeTypeSubImage || (segmentType != "" && segmentType != "SEG_START") {
+		return false
+	}
+
+	replaces := subscription.imaged
+	subscription.imaged = true
+	return replaces
+}
+
+// update keeps the clocks and the heartbeat interval the exchange confirms
+func (subscription *streamSubscription) update(initialClk, clk string, heartbeatMs int64) {
+	if heartbeatMs > 0 {
+		subscription.heartbeat = time.Duration(heartbeatMs) * time.Millisecond
+	}
+
+	if initialClk != "" {
+		subscription.initialClk = initialClk
+	}
+
+	if clk != "" {
+		subscription.clk = clk
+	}
+}
+
+// StreamLatency sums up the delay between the publish time of change
+// messages and their receipt
+type StreamLatency struct {
+	Count int64
+	Last  time.Duration
+	Max   time.Duration
+	Total time.Duration
+}
+
+func (latency StreamLatency) Mean() time.Duration {
+	if latency.Count == 0 {
+		return 0
+	}
+
+	return latency.Total / time.Duration(latency.Count)
+}
+
+func (latency *StreamLatency) add(publishTime int64, receivedAt time.Time) {
+	if publishTime <= 0 {
+		return
+	}
+
+	delay := receivedAt.Sub(streamTime(publishTime))
+	latency.Count++
+	latency.Last = delay
+	latency.Total += delay
+
+	if delay > latency.Max {
+		latency.Max = delay
+	}
+}
+
+// Stream is a connection to the Exchange Stream API. Messages carries the
+// decoded messages and closes when the stream stops.
+type Stream struct {
+	session  *Session
+	conn     net.Conn
+	reader   *bufio.Reader
+	writeM   sync.Mutex
+	m        sync.Mutex
+	lastID   int64
+	pending  map[int64]chan StatusMessage
+	messages chan interface{}
+	done     chan struct{}
+	closed   bool
+	err      error
+	latency  StreamLatency
+	dropped  int64
+
+	connectionID string
+
+	marketSubscription *marketSubscription
+	orderSubscription  *orderSubscription
+}
+
+func NewStream(session *Session) *Stream {
+	return &Stream{
+		session:  session,
+		pending:  map[int64]chan StatusMessage{},
+		messages: make(chan interface{}, StreamMessageBuffer),
+		done:     make(chan struct{}),
+	}
+}
+
+// Connect opens the connection, authenticates with the session token and
+// starts reading messages
+func (stream *Stream) Connect() error {
+	err := stream.connect()
+
+	if err != nil {
+		return err
+	}
+
+	go stream.readLoop()
+
+	return nil
+}
+
+// connect dials, authenticates and resumes the subscriptions. A session
+// error drops the token so the next attempt logs in again.
+func (stream *Stream) connect() error {
+	token, err := stream.session.GetToken()
+
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialStream(StreamEndpoint)
+
+	if err != nil {
+		return err
+	}
+
+	stream.writeM.Lock()
+	stream.m.Lock()
+	closed := stream.closed
+	stream.conn = conn
+	stream.reader = bufio.NewReader(conn)
+	stream.m.Unlock()
+	stream.writeM.Unlock()
+
+	if closed {
+		conn.Close()
+		return ErrStreamClosed
+	}
+
+	err = stream.handshake(token)
+
+	if isStreamErrorCode(err, streamSessionErrorCodes) {
+		stream.session.resetToken(token)
+	}
+
+	if err == nil {
+		err = stream.resubscribe()
+	}
+
+	if err != nil {
+		conn.Close()
+	}
+
+	return err
+}
+
+// resubscribe sends the current subscriptions with their clocks, so the
+// exchange only sends what changed since
+func (stream *Stream) resubscribe() error {
+	stream.m.Lock()
+	markets := stream.marketSubscription
+	orders := stream.orderSubscription
+	stream.m.Unlock()
+
+	var err error
+
+	if markets != nil {
+		err = stream.resume(markets.id, markets.message())
+	}
+
+	if err == nil && orders != nil {
+		err = stream.resume(orders.id, orders.message())
+	}
+
+	// the clocks are too old to resume from, the next attempt asks for images
+	if isStreamErrorCode(err, map[string]bool{"INVALID_CLOCK": true}) {
+		if markets != nil {
+			markets.resetClocks()
+		}
+
+		if orders != nil {
+			orders.resetClocks()
+		}
+	}
+
+	return err
+}
+
+// resume sends a subscription and handles the messages that arrive before
+// its status
+func (stream *Stream) resume(id int64, message interface{}) error {
+	err := stream.send(message)
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		stream.conn.SetReadDeadline(time.Now().Add(StreamTimeout))
+		line, err := stream.reader.ReadBytes('\n')
+
+		if err != nil {
+			return err
+		}
+
+		var status StatusMessage
+		err = json.Unmarshal(line, &status)
+
+		if err != nil {
+			return err
+		}
+
+		if status.Op == "status" && status.ID == id {
+			stream.conn.SetReadDeadline(time.Time{})
+			return status.err()
+		}
+
+		err = stream.handle(line, time.Now())
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handshake reads the connection message and authenticates before the read
+// loop starts
+func (stream *Stream) handshake(token string) error {
+	stream.conn.SetReadDeadline(time.Now().Add(StreamTimeout))
+	defer stream.conn.SetReadDeadline(time.Time{})
+
+	var connection ConnectionMessage
+	err := stream.readMessage(&connection)
+
+	if err != nil {
+		return err
+	}
+
+	if connection.Op != "connection" {
+		return fmt.Errorf("Unexpected stream message `%s`", connection.Op)
+	}
+
+	stream.m.Lock()
+	stream.connectionID = connection.ConnectionID
+	stream.m.Unlock()
+
+	err = stream.send(authenticationMessage{Op: "authentication", ID: stream.nextID(), AppKey: stream.session.account.ApplicationKey, Session: token})
+
+	if err != nil {
+		return err
+	}
+
+	var status StatusMessage
+	err = stream.readMessage(&status)
+
+	if err != nil {
+		return err
+	}
+
+	if status.Op != "status" {
+		return fmt.Errorf("Unexpected stream message `%s`", status.Op)
+	}
+
+	return status.err()
+}
+
+func (stream *Stream) readMessage(message interface{}) error {
+	line, err := stream.reader.ReadBytes('\n')
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(line, message)
+}
+
+// send writes a message followed by CRLF
+func (stream *Stream) send(message interface{}) error {
+	body, err := json.Marshal(message)
+
+	if err != nil {
+		return err
+	}
+
+	stream.writeM.Lock()
+	defer stream.writeM.Unlock()
+
+	if stream.conn == nil {
+		return ErrStreamNotConnected
+	}
+
+	stream.conn.SetWriteDeadline(time.Now().Add(StreamTimeout))
+	_, err = stream.conn.Write(append(body, '\r', '\n'))
+
+	return err
+}
+
+func (stream *Stream) nextID() int64 {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	stream.lastID++
+	return stream.lastID
+}
+
+// request sends a message with the given id and waits for its status
+func (stream *Stream) request(id int64, message interface{}) (StatusMessage, error) {
+	var statusCh = make(chan StatusMessage, 1)
+
+	stream.m.Lock()
+
+	if stream.closed {
+		stream.m.Unlock()
+		return StatusMessage{}, ErrStreamClosed
+	}
+
+	if stream.conn == nil {
+		stream.m.Unlock()
+		return StatusMessage{}, ErrStreamNotConnected
+	}
+
+	stream.pending[id] = statusCh
+	stream.m.Unlock()
+
+	defer func() {
+		stream.m.Lock()
+		delete(stream.pending, id)
+		stream.m.Unlock()
+	}()
+
+	err := stream.send(message)
+
+	if err != nil {
+		return StatusMessage{}, err
+	}
+
+	select {
+	case status, ok := <-statusCh:
+		if !ok && stream.isClosed() {
+			return StatusMessage{}, ErrStreamClosed
+		}
+
+		if !ok {
+			return StatusMessage{}, ErrStreamDisconnected
+		}
+
+		return status, status.err()
+	case <-time.After(StreamTimeout):
+		return StatusMessage{}, fmt.Errorf("Timed out waiting for stream request %d", id)
+	}
+}
+
+// Heartbeat checks the connection, the exchange closes connections idle
+// for longer than its timeout
+func (stream *Stream) Heartbeat() (StatusMessage, error) {
+	id := stream.nextID()
+	return stream.request(id, HeartbeatMessage{Op: "heartbeat", ID: id})
+}
+
+// readLoop handles messages until the connection drops, then reconnects
+// unless the stream was closed or the failure is fatal
+func (stream *Stream) readLoop() {
+	for {
+		err := stream.readMessages()
+
+		if !stream.isClosed() && StreamReconnectAttempts > 0 && !isStreamErrorCode(err, streamFatalErrorCodes) {
+			err = stream.reconnect(err)
+
+			if err == nil {
+				continue
+			}
+		}
+
+		stream.stop(err)
+		return
+	}
+}
+
+// readMessages handles messages until the connection fails or stays silent
+// for longer than the subscriptions' heartbeats allow
+func (stream *Stream) readMessages() error {
+	for {
+		if timeout := stream.heartbeatTimeout(); timeout > 0 {
+			stream.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		line, err := stream.reader.ReadBytes('\n')
+
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return ErrMissedHeartbeat
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = stream.handle(line, time.Now())
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// heartbeatTimeout returns how long the connection may stay silent, zero
+// without subscriptions
+func (stream *Stream) heartbeatTimeout() time.Duration {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	var heartbeat time.Duration
+
+	if stream.marketSubscription != nil {
+		heartbeat = stream.marketSubscription.heartbeat
+	}
+
+	if stream.orderSubscription != nil && (heartbeat == 0 || stream.orderSubscription.heartbeat < heartbeat) {
+		heartbeat = stream.orderSubscription.heartbeat
+	}
+
+	return heartbeat * time.Duration(StreamMissedHeartbeats)
+}
+
+func (stream *Stream) recordLatency(publishTime int64, receivedAt time.Time) {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	stream.latency.add(publishTime, receivedAt)
+}
+
+// Latency returns the publish to receipt delays of the change messages
+// received so far
+func (stream *Stream) Latency() StreamLatency {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	return stream.latency
+}
+
+// reconnectDelay returns the wait before the given attempt
+func reconnectDelay(attempt int) time.Duration {
+	if attempt <= 1 {
+		return 0
+	}
+
+	delay := StreamReconnectDelay
+
+	for i := 2; i < attempt && delay < StreamMaxReconnectDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > StreamMaxReconnectDelay {
+		delay = StreamMaxReconnectDelay
+	}
+
+	return delay
+}
+
+func (stream *Stream) reconnect(cause error) error {
+	stream.conn.Close()
+	stream.failPending()
+
+	var err = cause
+
+	for attempt := 1; attempt <= StreamReconnectAttempts; attempt++ {
+		select {
+		case <-time.After(reconnectDelay(attempt)):
+		case <-stream.done:
+			return ErrStreamClosed
+		}
+
+		err = stream.connect()
+
+		if err == nil {
+			stream.emitEvent(ReconnectEvent{ConnectionID: stream.ConnectionID(), Attempts: attempt, Cause: cause})
+			return nil
+		}
+
+		if isStreamErrorCode(err, streamFatalErrorCodes) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// failPending unblocks the requests waiting for a status
+func (stream *Stream) failPending() {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	for id, statusCh := range stream.pending {
+		close(statusCh)
+		delete(stream.pending, id)
+	}
+}
+
+func (stream *Stream) isClosed() bool {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	return stream.closed
+}
+
+func (stream *Stream) handle(line []byte, receivedAt time.Time) error {
+	var envelope struct {
+		Op string `json:"op"`
+	}
+
+	err := json.Unmarshal(line, &envelope)
+
+	if err != nil {
+		return err
+	}
+
+	switch envelope.Op {
+	case "connection":
+		var connection ConnectionMessage
+		err = json.Unmarshal(line, &connection)
+
+		if err != nil {
+			return err
+		}
+
+		stream.emit(connection)
+	case "status":
+		var status StatusMessage
+		err = json.Unmarshal(line, &status)
+
+		if err != nil {
+			return err
+		}
+
+		stream.m.Lock()
+		statusCh, ok := stream.pending[status.ID]
+		stream.m.Unlock()
+
+		if ok {
+			statusCh <- status
+		}
+
+		stream.emit(status)
+
+		if status.ConnectionClosed {
+			return status.err()
+		}
+	case "mcm":
+		var message MarketChangeMessage
+		err = json.Unmarshal(line, &message)
+
+		if err != nil {
+			return err
+		}
+
+		message.ReceivedAt = receivedAt
+		stream.recordLatency(message.PublishTime, receivedAt)
+		stream.handleMarketChange(message)
+	case "ocm":
+		var message OrderChangeMessage
+		err = json.Unmarshal(line, &message)
+
+		if err != nil {
+			return err
+		}
+
+		message.ReceivedAt = receivedAt
+		stream.recordLatency(message.PublishTime, receivedAt)
+		stream.handleOrderChange(message)
+	}
+
+	return nil
+}
+
+// emit passes a message to the reader of Messages without blocking, so a
+// slow reader never holds up status replies
+func (stream *Stream) emit(message interface{}) {
+	select {
+	case stream.messages <- message:
+	default:
+		stream.m.Lock()
+		stream.dropped++
+		stream.m.Unlock()
+	}
+}
+
+// emitEvent passes a ReconnectEvent or CacheResetEvent to the reader of
+// Messages. When the buffer is full the queued messages are dropped, except
+// for earlier events, and only a buffer holding nothing but events is
+// waited on.
+func (stream *Stream) emitEvent(event interface{}) {
+	select {
+	case stream.messages <- event:
+		return
+	default:
+	}
+
+	var events []interface{}
+	var dropped int64
+
+	for drained := false; !drained; {
+		select {
+		case message := <-stream.messages:
+			switch message.(type) {
+			case ReconnectEvent, CacheResetEvent:
+				events = append(events, message)
+			default:
+				dropped++
+			}
+		default:
+			drained = true
+		}
+	}
+
+	stream.m.Lock()
+	stream.dropped += dropped
+	stream.m.Unlock()
+
+	for _, message := range append(events, event) {
+		select {
+		case stream.messages <- message:
+		case <-stream.done:
+			return
+		}
+	}
+}
+
+// stop fails pending requests and closes Messages, err is kept unless the
+// stream was closed by Close
+func (stream *Stream) stop(err error) {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	if !stream.closed {
+		stream.err = err
+		stream.closed = true
+		stream.conn.Close()
+		close(stream.done)
+	}
+
+	for id, statusCh := range stream.pending {
+		close(statusCh)
+		delete(stream.pending, id)
+	}
+
+	close(stream.messages)
+}
+
+// Messages returns the channel of ConnectionMessage, StatusMessage,
+// MarketChangeMessage, OrderChangeMessage, ReconnectEvent and
+// CacheResetEvent values, change messages are applied to their cache first
+func (stream *Stream) Messages() <-chan interface{} {
+	return stream.messages
+}
+
+// ConnectionID returns the id the exchange gave the current connection
+func (stream *Stream) ConnectionID() string {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	return stream.connectionID
+}
+
+// Dropped returns the number of messages dropped because the buffer of
+// Messages was full
+func (stream *Stream) Dropped() int64 {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	return stream.dropped
+}
+
+// Err returns the error that stopped the stream, nil after Close
+func (stream *Stream) Err() error {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	return stream.err
+}
+
+func (stream *Stream) Close() error {
+	stream.m.Lock()
+	defer stream.m.Unlock()
+
+	if stream.closed {
+		return nil
+	}
+
+	stream.closed = true
+	close(stream.done)
+
+	if stream.conn == nil {
+		return nil
+	}
+
+	return stream.conn.Close()
+}
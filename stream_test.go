@@ -0,0 +1,388 @@
+package betfair
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+type mockStreamServer struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// read decodes the next message the client sent
+func (server *mockStreamServer) read(message interface{}) {
+	line, err := server.reader.ReadBytes('\n')
+
+	if err != nil {
+		server.t.Error(err)
+		return
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		server.t.Errorf("Message not CRLF terminated: %q", line)
+	}
+
+	if err := json.Unmarshal(line, message); err != nil {
+		server.t.Error(err)
+	}
+}
+
+// write sends message compacted onto one line
+func (server *mockStreamServer) write(message string) {
+	var line bytes.Buffer
+
+	if err := json.Compact(&line, []byte(message)); err != nil {
+		server.t.Error(err)
+		return
+	}
+
+	if _, err := server.conn.Write(append(line.Bytes(), '\r', '\n')); err != nil {
+		server.t.Error(err)
+	}
+}
+
+// accept plays the connection and authentication handshake
+func (server *mockStreamServer) accept() {
+	server.write(`{"op":"connection","connectionId":"002-051134157842-432409"}`)
+
+	var authentication authenticationMessage
+	server.read(&authentication)
+
+	if authentication.Op != "authentication" || authentication.AppKey != "key" || authentication.Session != "mock" {
+		server.t.Errorf("Unexpected authentication %+v", authentication)
+	}
+
+	server.write(`{"op":"status","id":1,"statusCode":"SUCCESS","connectionClosed":false}`)
+}
+
+// getMockStream returns a stream whose connections are served by handler
+func getMockStream(t *testing.T, handler func(server *mockStreamServer)) *Stream {
+	dial := dialStream
+
+	dialStream = func(endpoint string) (net.Conn, error) {
+		client, conn := net.Pipe()
+		go handler(&mockStreamServer{t: t, conn: conn, reader: bufio.NewReader(conn)})
+		return client, nil
+	}
+
+	reconnectDelay := StreamReconnectDelay
+	StreamReconnectDelay = time.Millisecond
+
+	t.Cleanup(func() {
+		dialStream = dial
+		StreamReconnectDelay = reconnectDelay
+	})
+
+	session, err := NewSession(&Account{ApplicationKey: "key"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.ssoid = "mock"
+
+	return NewStream(session)
+}
+
+func TestStreamAuthenticationAndHeartbeat(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+
+		var heartbeat HeartbeatMessage
+		server.read(&heartbeat)
+
+		if heartbeat.Op != "heartbeat" || heartbeat.ID != 2 {
+			t.Errorf("Unexpected heartbeat %+v", heartbeat)
+		}
+
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS","connectionClosed":false,"connectionsAvailable":9}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if stream.ConnectionID() != "002-051134157842-432409" {
+		t.Errorf("Unexpected connection id %s", stream.ConnectionID())
+	}
+
+	status, err := stream.Heartbeat()
+
+	if err != nil || status.ConnectionsAvailable != 9 {
+		t.Errorf("Unexpected heartbeat status %v, %+v", err, status)
+	}
+
+	if message := <-stream.Messages(); message.(StatusMessage).ID != 2 {
+		t.Errorf("Unexpected message %+v", message)
+	}
+}
+
+func TestStreamRequestsBeforeConnect(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		t.Error("Unexpected connection")
+	})
+
+	defer stream.Close()
+
+	if _, err := stream.Heartbeat(); err != ErrStreamNotConnected {
+		t.Errorf("Expected ErrStreamNotConnected from Heartbeat, got %v", err)
+	}
+
+	if _, err := stream.SubscribeMarkets(MarketSubscriptionRequest{}); err != ErrStreamNotConnected {
+		t.Errorf("Expected ErrStreamNotConnected from SubscribeMarkets, got %v", err)
+	}
+
+	if _, err := stream.SubscribeOrders(OrderSubscriptionRequest{}); err != ErrStreamNotConnected {
+		t.Errorf("Expected ErrStreamNotConnected from SubscribeOrders, got %v", err)
+	}
+}
+
+func TestStreamFullBufferDropsMessages(t *testing.T) {
+	messageBuffer := StreamMessageBuffer
+	StreamMessageBuffer = 1
+	defer func() { StreamMessageBuffer = messageBuffer }()
+
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+
+		for i := 0; i < 3; i++ {
+			server.write(`{"op":"status","id":99,"statusCode":"SUCCESS"}`)
+		}
+
+		var heartbeat HeartbeatMessage
+		server.read(&heartbeat)
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := stream.Heartbeat(); err != nil {
+		t.Errorf("Heartbeat should not wait for Messages to be read, got %v", err)
+	}
+
+	if dropped := stream.Dropped(); dropped < 2 {
+		t.Errorf("Expected dropped messages, got %d", dropped)
+	}
+}
+
+func TestStreamFullBufferKeepsEvents(t *testing.T) {
+	messageBuffer := StreamMessageBuffer
+	StreamMessageBuffer = 2
+	defer func() { StreamMessageBuffer = messageBuffer }()
+
+	var connections int
+	var resubscribed = make(chan bool)
+
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		connections++
+		server.accept()
+
+		var subscription marketSubscriptionMessage
+		server.read(&subscription)
+		server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+
+		if connections > 1 {
+			server.write(`{"op":"mcm","id":2,"clk":"C3","ct":"RESUB_DELTA","mc":[{"id":"1.1","rc":[{"id":101,"batb":[[0,2.56,4]]}]}]}`)
+			resubscribed <- true
+
+			var heartbeat HeartbeatMessage
+			server.read(&heartbeat)
+			server.write(fmt.Sprintf(`{"op":"status","id":%d,"statusCode":"SUCCESS"}`, heartbeat.ID))
+			return
+		}
+
+		for i := 0; i < 2; i++ {
+			server.write(`{"op":"mcm","id":2,"initialClk":"I1","clk":"C1","ct":"SUB_IMAGE","mc":[{"id":"1.1","img":true,"rc":[{"id":101,"batb":[[0,2.5,10]]}]}]}`)
+
+			for j := 0; j < 3; j++ {
+				server.write(`{"op":"mcm","id":2,"clk":"C2","mc":[{"id":"1.1","rc":[{"id":101,"batb":[[0,2.52,8]]}]}]}`)
+			}
+		}
+
+		server.conn.Close()
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := stream.SubscribeMarkets(MarketSubscriptionRequest{MarketFilter: StreamMarketFilter{MarketIDs: []string{"1.1"}}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	<-resubscribed
+
+	if _, err := stream.Heartbeat(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var resets, reconnects int
+
+	for queued := len(stream.Messages()); queued > 0; queued-- {
+		switch (<-stream.Messages()).(type) {
+		case CacheResetEvent:
+			resets++
+		case ReconnectEvent:
+			reconnects++
+		}
+	}
+
+	if resets != 1 || reconnects != 1 || stream.Dropped() == 0 {
+		t.Errorf("Got %d resets and %d reconnects with %d dropped", resets, reconnects, stream.Dropped())
+	}
+}
+
+func TestStreamAuthenticationFailure(t *testing.T) {
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.write(`{"op":"connection","connectionId":"002-051134157842-432409"}`)
+
+		var authentication authenticationMessage
+		server.read(&authentication)
+		server.write(`{"op":"status","id":1,"statusCode":"FAILURE","errorCode":"NO_APP_KEY","errorMessage":"AppKey is not configured for service","connectionClosed":true}`)
+		server.conn.Close()
+	})
+
+	err := stream.Connect()
+
+	if statusErr, ok := err.(*StreamStatusError); !ok || statusErr.ErrorCode != "NO_APP_KEY" || !statusErr.ConnectionClosed {
+		t.Errorf("Expected a NO_APP_KEY error, got %v", err)
+	}
+}
+
+func TestStreamClosedByExchange(t *testing.T) {
+	reconnectAttempts := StreamReconnectAttempts
+	StreamReconnectAttempts = 0
+	defer func() { StreamReconnectAttempts = reconnectAttempts }()
+
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		server.accept()
+		server.write(`{"op":"status","statusCode":"FAILURE","errorCode":"TIMEOUT","errorMessage":"Timed out","connectionClosed":true}`)
+		server.conn.Close()
+	})
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for range stream.Messages() {
+	}
+
+	if statusErr, ok := stream.Err().(*StreamStatusError); !ok || statusErr.ErrorCode != "TIMEOUT" {
+		t.Errorf("Expected a TIMEOUT error, got %v", stream.Err())
+	}
+
+	if _, err := stream.Heartbeat(); err != ErrStreamClosed {
+		t.Errorf("Expected ErrStreamClosed, got %v", err)
+	}
+}
+
+func TestStreamReconnectResumes(t *testing.T) {
+	var connections int
+
+	stream := getMockStream(t, func(server *mockStreamServer) {
+		connections++
+		server.accept()
+
+		var subscription marketSubscriptionMessage
+		server.read(&subscription)
+
+		switch connections {
+		case 1:
+			server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+			server.write(`{"op":"mcm","id":2,"initialClk":"I1","clk":"C1","ct":"SUB_IMAGE","mc":[
+				{"id":"1.1","img":true,"rc":[{"id":101,"batb":[[0,2.5,10]]}]},
+				{"id":"1.2","img":true,"rc":[{"id":201,"batb":[[0,3.5,10]]}]}]}`)
+			server.conn.Close()
+		case 2:
+			if subscription.ID != 2 || subscription.InitialClk != "I1" || subscription.Clk != "C1" {
+				t.Errorf("Unexpected resubscription %+v", subscription)
+			}
+
+			server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+			server.write(`{"op":"mcm","id":2,"clk":"C2","ct":"RESUB_DELTA","mc":[{"id":"1.1","rc":[{"id":101,"batb":[[0,2.52,8]]}]}]}`)
+			server.conn.Close()
+		case 3:
+			if subscription.Clk != "C2" {
+				t.Errorf("Unexpected resubscription %+v", subscription)
+			}
+
+			server.write(`{"op":"status","id":2,"statusCode":"SUCCESS"}`)
+			server.write(`{"op":"mcm","id":2,"initialClk":"I3","clk":"C3","ct":"SUB_IMAGE","mc":[{"id":"1.1","img":true,"rc":[{"id":101,"batb":[[0,2.54,6]]}]}]}`)
+		}
+	})
+
+	defer stream.Close()
+
+	if err := stream.Connect(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	cache, err := stream.SubscribeMarkets(MarketSubscriptionRequest{MarketFilter: StreamMarketFilter{EventTypeIDs: []string{"7"}}})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var changes, reconnects, resets int
+
+	for changes < 3 {
+		message, ok := <-stream.Messages()
+
+		if !ok {
+			t.Errorf("Stream stopped: %v", stream.Err())
+			return
+		}
+
+		switch message := message.(type) {
+		case MarketChangeMessage:
+			changes++
+
+			if changes == 2 {
+				if book, _ := cache.MarketBook("1.1"); book.Runners[0].EX.AvailableToBack[0].Price != 2.52 {
+					t.Errorf("Delta not applied %+v", book.Runners[0].EX)
+				}
+			}
+		case ReconnectEvent:
+			reconnects++
+		case CacheResetEvent:
+			resets++
+
+			if message.Op != "mcm" || message.SubscriptionID != 2 || changes != 2 {
+				t.Errorf("Unexpected reset %+v after %d changes", message, changes)
+			}
+		}
+	}
+
+	if reconnects != 2 || resets != 1 {
+		t.Errorf("Got %d reconnects and %d resets", reconnects, resets)
+	}
+
+	books := cache.MarketBooks()
+
+	if len(books) != 1 || books[0].Runners[0].EX.AvailableToBack[0].Price != 2.54 {
+		t.Errorf("Unexpected books after the full image %+v", books)
+	}
+}
@@ -2,6 +2,7 @@ package betfair
 
 import (
 	"crypto/tls"
+	"math"
 	"time"
 )
 
@@ -19,6 +20,7 @@ type Account struct {
 	Certificate    tls.Certificate
 	KeepAlive      bool
 	LoginMethod    LoginMethod
+	Currency       string
 }
 
 type TimeRange struct {
@@ -26,6 +28,19 @@ type TimeRange struct {
 	To   time.Time `json:"to,omitempty"`
 }
 
+type TimeGranularity string
+
+const (
+	TimeGranularityDays    TimeGranularity = "DAYS"
+	TimeGranularityHours   TimeGranularity = "HOURS"
+	TimeGranularityMinutes TimeGranularity = "MINUTES"
+)
+
+type TimeRangeResult struct {
+	TimeRange   TimeRange `json:"timeRange"`
+	MarketCount int64     `json:"marketCount"`
+}
+
 type MarketFilter struct {
 	TextQuery          string     `json:"textQuery,omitempty"`
 	ExchangeIDs        []string   `json:"exchangeIds,omitempty"`
@@ -129,21 +144,34 @@ type MarketTypeResult struct {
 }
 
 type MarketDescription struct {
-	PersistenceEnabled bool    `json:"persistenceEnabled"`
-	BspMarket          bool    `json:"bspMarket"`
-	MarketTime         string  `json:"marketTime"`
-	SuspendTime        string  `json:"suspendTime"`
-	SettleTime         string  `json:"settleTime"`
-	BettingType        string  `json:"bettingType"`
-	TurnInPlayEnabled  bool    `json:"turnInPlayEnabled"`
-	MarketType         string  `json:"marketType"`
-	Regulator          string  `json:"regulator"`
-	MarketBaseRate     float64 `json:"marketBaseRate"`
-	DiscountAllowed    bool    `json:"discountAllowed"`
-	Wallet             string  `json:"wallet"`
-	Rules              string  `json:"rules"`
-	RulesHasDate       bool    `json:"rulesHasDate"`
-	Certifications     string  `json:"certifications"`
+	PersistenceEnabled bool        `json:"persistenceEnabled"`
+	BspMarket          bool        `json:"bspMarket"`
+	MarketTime         time.Time   `json:"marketTime"`
+	SuspendTime        time.Time   `json:"suspendTime"`
+	SettleTime         time.Time   `json:"settleTime"`
+	BettingType        BettingType `json:"bettingType"`
+	TurnInPlayEnabled  bool        `json:"turnInPlayEnabled"`
+	MarketType         string      `json:"marketType"`
+	Regulator          string      `json:"regulator"`
+	MarketBaseRate     float64     `json:"marketBaseRate"`
+	DiscountAllowed    bool        `json:"discountAllowed"`
+	Wallet             string      `json:"wallet"`
+	Rules              string      `json:"rules"`
+	RulesHasDate       bool        `json:"rulesHasDate"`
+	Certifications     string      `json:"certifications"`
+
+	PriceLadderDescription *PriceLadderDescription `json:"priceLadderDescription"`
+	LineRangeInfo          *MarketLineRangeInfo    `json:"lineRangeInfo"`
+}
+
+// PriceLadder returns the ladder the market trades on, CLASSIC when the
+// description does not name one
+func (description MarketDescription) PriceLadder() PriceLadder {
+	if description.PriceLadderDescription == nil || description.PriceLadderDescription.Type == "" {
+		return ClassicPriceLadder
+	}
+
+	return PriceLadder{Type: description.PriceLadderDescription.Type, LineRange: description.LineRangeInfo}
 }
 
 type RunnerCatalogue struct {
@@ -157,7 +185,7 @@ type RunnerCatalogue struct {
 type MarketCatalogue struct {
 	MarketID        string             `json:"marketId"`
 	MarketName      string             `json:"marketName"`
-	MarketStartTime string             `json:"marketStartTime"`
+	MarketStartTime time.Time          `json:"marketStartTime"`
 	Description     *MarketDescription `json:"description"`
 	Runners         []RunnerCatalogue  `json:"runners"`
 	TotalMatched    float64            `json:"totalMatched"`
@@ -171,6 +199,323 @@ type PriceSize struct {
 	Size  float64 `json:"size"`
 }
 
+// Price is an odds value, see PriceLadder for the valid increments
+type Price float64
+
+type PriceLadderType string
+
+const (
+	PriceLadderClassic   PriceLadderType = "CLASSIC"
+	PriceLadderFinest    PriceLadderType = "FINEST"
+	PriceLadderLineRange PriceLadderType = "LINE_RANGE"
+)
+
+type RoundDirection int
+
+const (
+	RoundNearest RoundDirection = iota
+	RoundUp
+	RoundDown
+)
+
+type PriceLadderDescription struct {
+	Type PriceLadderType `json:"type"`
+}
+
+type MarketLineRangeInfo struct {
+	MaxUnitValue float64 `json:"maxUnitValue"`
+	MinUnitValue float64 `json:"minUnitValue"`
+	Interval     float64 `json:"interval"`
+	MarketUnit   string  `json:"marketUnit"`
+}
+
+// PriceLadder describes the prices accepted on a market, LINE_RANGE ladders
+// are built from the market line range info
+type PriceLadder struct {
+	Type      PriceLadderType
+	LineRange *MarketLineRangeInfo
+}
+
+var ClassicPriceLadder = PriceLadder{Type: PriceLadderClassic}
+
+var FinestPriceLadder = PriceLadder{Type: PriceLadderFinest}
+
+// ladder increments in hundredths, each band covers [from, to)
+type priceBand struct {
+	from int64
+	to   int64
+	step int64
+}
+
+var classicPriceBands = []priceBand{
+	{101, 200, 1},
+	{200, 300, 2},
+	{300, 400, 5},
+	{400, 600, 10},
+	{600, 1000, 20},
+	{1000, 2000, 50},
+	{2000, 3000, 100},
+	{3000, 5000, 200},
+	{5000, 10000, 500},
+	{10000, 100000, 1000},
+}
+
+var finestPriceBands = []priceBand{
+	{101, 100000, 1},
+}
+
+func toHundredths(value float64) int64 {
+	return int64(math.Round(value * 100))
+}
+
+func (ladder PriceLadder) bands() []priceBand {
+	switch ladder.Type {
+	case PriceLadderClassic:
+		return classicPriceBands
+	case PriceLadderFinest:
+		return finestPriceBands
+	case PriceLadderLineRange:
+		if ladder.LineRange == nil || ladder.LineRange.Interval <= 0 {
+			return nil
+		}
+
+		from := toHundredths(ladder.LineRange.MinUnitValue)
+		to := toHundredths(ladder.LineRange.MaxUnitValue)
+		return []priceBand{{from, to, toHundredths(ladder.LineRange.Interval)}}
+	}
+
+	return nil
+}
+
+// position returns the index of the highest tick not above cents, -1 below
+// the ladder, and whether cents is a tick
+func (ladder PriceLadder) position(cents int64) (int64, bool) {
+	bands := ladder.bands()
+
+	if len(bands) == 0 || cents < bands[0].from {
+		return -1, false
+	}
+
+	var index int64
+
+	for _, band := range bands {
+		if cents < band.to {
+			return index + (cents-band.from)/band.step, (cents-band.from)%band.step == 0
+		}
+
+		index += (band.to - band.from) / band.step
+	}
+
+	return index, cents == bands[len(bands)-1].to
+}
+
+func (ladder PriceLadder) priceAt(index int64) int64 {
+	bands := ladder.bands()
+
+	if index < 0 {
+		index = 0
+	}
+
+	for _, band := range bands {
+		ticks := (band.to - band.from) / band.step
+
+		if index < ticks {
+			return band.from + index*band.step
+		}
+
+		index -= ticks
+	}
+
+	return bands[len(bands)-1].to
+}
+
+// Min returns the lowest price of the ladder
+func (ladder PriceLadder) Min() Price {
+	if len(ladder.bands()) == 0 {
+		return 0
+	}
+
+	return Price(float64(ladder.priceAt(0)) / 100)
+}
+
+// Max returns the highest price of the ladder
+func (ladder PriceLadder) Max() Price {
+	bands := ladder.bands()
+
+	if len(bands) == 0 {
+		return 0
+	}
+
+	return Price(float64(bands[len(bands)-1].to) / 100)
+}
+
+// IsValid reports whether price is a tick of the ladder
+func (ladder PriceLadder) IsValid(price Price) bool {
+	cents := toHundredths(float64(price))
+
+	if math.Abs(float64(price)*100-float64(cents)) > 1e-6 {
+		return false
+	}
+
+	index, exact := ladder.position(cents)
+	return index >= 0 && exact
+}
+
+// Round moves price to a tick of the ladder in the given direction, prices
+// outside of the ladder are clamped to its bounds
+func (ladder PriceLadder) Round(price Price, direction RoundDirection) Price {
+	if len(ladder.bands()) == 0 {
+		return price
+	}
+
+	// the tick below is found from the unrounded price, so only exact
+	// ticks skip the rounding
+	value := float64(price) * 100
+	cents := int64(math.Floor(value + 1e-6))
+	index, exact := ladder.position(cents)
+
+	if exact && math.Abs(value-float64(cents)) < 1e-6 {
+		return Price(float64(cents) / 100)
+	}
+
+	lower := ladder.priceAt(index)
+	upper := ladder.priceAt(index + 1)
+
+	if index < 0 {
+		lower = upper
+	}
+
+	switch direction {
+	case RoundUp:
+		return Price(float64(upper) / 100)
+	case RoundDown:
+		return Price(float64(lower) / 100)
+	}
+
+	if float64(upper)-value < value-float64(lower) {
+		return Price(float64(upper) / 100)
+	}
+
+	return Price(float64(lower) / 100)
+}
+
+// TickUp moves price the given number of ticks up the ladder, a price off
+// the ladder is rounded up first
+func (ladder PriceLadder) TickUp(price Price, ticks int) Price {
+	return ladder.move(ladder.Round(price, RoundUp), int64(ticks))
+}
+
+// TickDown moves price the given number of ticks down the ladder, a price
+// off the ladder is rounded down first
+func (ladder PriceLadder) TickDown(price Price, ticks int) Price {
+	return ladder.move(ladder.Round(price, RoundDown), -int64(ticks))
+}
+
+// Ticks returns the number of ticks from one price to another, negative
+// when to is below from
+func (ladder PriceLadder) Ticks(from, to Price) int {
+	fromIndex, _ := ladder.position(toHundredths(float64(ladder.Round(from, RoundNearest))))
+	toIndex, _ := ladder.position(toHundredths(float64(ladder.Round(to, RoundNearest))))
+	return int(toIndex - fromIndex)
+}
+
+func (ladder PriceLadder) move(price Price, ticks int64) Price {
+	if len(ladder.bands()) == 0 {
+		return price
+	}
+
+	index, _ := ladder.position(toHundredths(float64(price)))
+	maxIndex, _ := ladder.position(toHundredths(float64(ladder.Max())))
+	index += ticks
+
+	if index > maxIndex {
+		index = maxIndex
+	}
+
+	return Price(float64(ladder.priceAt(index)) / 100)
+}
+
+// IsValid reports whether price is on the CLASSIC ladder
+func (price Price) IsValid() bool {
+	return ClassicPriceLadder.IsValid(price)
+}
+
+// TickUp moves price up the CLASSIC ladder
+func (price Price) TickUp(ticks int) Price {
+	return ClassicPriceLadder.TickUp(price, ticks)
+}
+
+// TickDown moves price down the CLASSIC ladder
+func (price Price) TickDown(ticks int) Price {
+	return ClassicPriceLadder.TickDown(price, ticks)
+}
+
+// Round moves price to the CLASSIC ladder in the given direction
+func (price Price) Round(direction RoundDirection) Price {
+	return ClassicPriceLadder.Round(price, direction)
+}
+
+// The enum types below decode any string, so values the exchange adds later
+// are kept as sent. IsValid reports whether a value is one defined here.
+type BettingType string
+
+const (
+	BettingTypeOdds                    BettingType = "ODDS"
+	BettingTypeLine                    BettingType = "LINE"
+	BettingTypeRange                   BettingType = "RANGE"
+	BettingTypeAsianHandicapDoubleLine BettingType = "ASIAN_HANDICAP_DOUBLE_LINE"
+	BettingTypeAsianHandicapSingleLine BettingType = "ASIAN_HANDICAP_SINGLE_LINE"
+	BettingTypeFixedOdds               BettingType = "FIXED_ODDS"
+)
+
+func (bettingType BettingType) IsValid() bool {
+	switch bettingType {
+	case BettingTypeOdds, BettingTypeLine, BettingTypeRange, BettingTypeAsianHandicapDoubleLine, BettingTypeAsianHandicapSingleLine, BettingTypeFixedOdds:
+		return true
+	}
+
+	return false
+}
+
+type MarketStatus string
+
+const (
+	MarketStatusInactive  MarketStatus = "INACTIVE"
+	MarketStatusOpen      MarketStatus = "OPEN"
+	MarketStatusSuspended MarketStatus = "SUSPENDED"
+	MarketStatusClosed    MarketStatus = "CLOSED"
+)
+
+func (status MarketStatus) IsValid() bool {
+	switch status {
+	case MarketStatusInactive, MarketStatusOpen, MarketStatusSuspended, MarketStatusClosed:
+		return true
+	}
+
+	return false
+}
+
+type RunnerStatus string
+
+const (
+	RunnerStatusActive        RunnerStatus = "ACTIVE"
+	RunnerStatusWinner        RunnerStatus = "WINNER"
+	RunnerStatusLoser         RunnerStatus = "LOSER"
+	RunnerStatusPlaced        RunnerStatus = "PLACED"
+	RunnerStatusRemovedVacant RunnerStatus = "REMOVED_VACANT"
+	RunnerStatusRemoved       RunnerStatus = "REMOVED"
+	RunnerStatusHidden        RunnerStatus = "HIDDEN"
+)
+
+func (status RunnerStatus) IsValid() bool {
+	switch status {
+	case RunnerStatusActive, RunnerStatusWinner, RunnerStatusLoser, RunnerStatusPlaced, RunnerStatusRemovedVacant, RunnerStatusRemoved, RunnerStatusHidden:
+		return true
+	}
+
+	return false
+}
+
 type StartingPrices struct {
 	NearPrice         string      `json:"nearPrice"`
 	FarPrice          string      `json:"farPrice"`
@@ -186,28 +531,28 @@ type ExchangePrices struct {
 }
 
 type RunnerOrder struct {
-	BetID     string `json:"betId"`
-	OrderType string `json:"orderType"`
-	Status    string `json:"status"`
+	BetID     string      `json:"betId"`
+	OrderType OrderType   `json:"orderType"`
+	Status    OrderStatus `json:"status"`
 }
 
 type RunnerMatch struct {
-	BetID     string  `json:"betId"`
-	MatchID   string  `json:"matchId"`
-	Side      string  `json:"side"`
-	Price     float64 `json:"price"`
-	Size      float64 `json:"size"`
-	MatchDate string  `json:"matchDate"`
+	BetID     string    `json:"betId"`
+	MatchID   string    `json:"matchId"`
+	Side      Side      `json:"side"`
+	Price     float64   `json:"price"`
+	Size      float64   `json:"size"`
+	MatchDate time.Time `json:"matchDate"`
 }
 
 type Runner struct {
 	SelectionID      int64           `json:"selectionId"`
 	Handicap         float64         `json:"handicap"`
-	Status           string          `json:"status"`
+	Status           RunnerStatus    `json:"status"`
 	AdjustmentFactor float64         `json:"adjustmentFactor"`
 	LastPriceTraded  float64         `json:"lastPriceTraded"`
 	TotalMatched     float64         `json:"totalMatched"`
-	RemovalDate      string          `json:"removalDate"`
+	RemovalDate      *time.Time      `json:"removalDate"`
 	SP               *StartingPrices `json:"sp"`
 	EX               *ExchangePrices `json:"ex"`
 	Orders           []RunnerOrder   `json:"orders"`
@@ -215,23 +560,23 @@ type Runner struct {
 }
 
 type MarketBook struct {
-	MarketID              string     `json:"marketId"`
-	IsMarketDataDelayed   bool       `json:"isMarketDataDelayed"`
-	Status                string     `json:"status"`
-	BetDelay              int64      `json:"betDelay"`
-	Runners               []Runner   `json:"runners"`
-	BspReconciled         bool       `json:"bspReconciled"`
-	Complete              bool       `json:"complete"`
-	Inplay                bool       `json:"inplay"`
-	NumberOfWinners       int64      `json:"numberOfWinners"`
-	NumberOfRunners       int64      `json:"numberOfRunners"`
-	NumberOfActiveRunners int64      `json:"numberOfActiveRunners"`
-	LastMatchTime         *time.Time `json:"lastMatchTime"`
-	TotalMatched          float64    `json:"totalMatched"`
-	TotalAvailable        float64    `json:"totalAvailable"`
-	CrossMatching         bool       `json:"crossMatching"`
-	RunnersVoidable       bool       `json:"runnersVoidable"`
-	Version               int64      `json:"version"`
+	MarketID              string       `json:"marketId"`
+	IsMarketDataDelayed   bool         `json:"isMarketDataDelayed"`
+	Status                MarketStatus `json:"status"`
+	BetDelay              int64        `json:"betDelay"`
+	Runners               []Runner     `json:"runners"`
+	BspReconciled         bool         `json:"bspReconciled"`
+	Complete              bool         `json:"complete"`
+	Inplay                bool         `json:"inplay"`
+	NumberOfWinners       int64        `json:"numberOfWinners"`
+	NumberOfRunners       int64        `json:"numberOfRunners"`
+	NumberOfActiveRunners int64        `json:"numberOfActiveRunners"`
+	LastMatchTime         *time.Time   `json:"lastMatchTime"`
+	TotalMatched          float64      `json:"totalMatched"`
+	TotalAvailable        float64      `json:"totalAvailable"`
+	CrossMatching         bool         `json:"crossMatching"`
+	RunnersVoidable       bool         `json:"runnersVoidable"`
+	Version               int64        `json:"version"`
 }
 
 type ExBestOffersOverrides struct {
@@ -250,57 +595,375 @@ type PriceProjection struct {
 }
 
 type CurrentOrderSumary struct {
-	BetID               string    `json:"betId"`
-	MarketID            string    `json:"marketId"`
-	SelectionID         int64     `json:"selectionId"`
-	Handicap            float64   `json:"handicap"`
-	PriceSize           PriceSize `json:"priceSize"`
-	BSPLiability        float64   `json:"bspLiability"`
-	Side                string    `json:"side"`
-	Status              string    `json:"status"`
-	PersistenceType     string    `json:"persistenceType"`
-	OrderType           string    `json:"orderType"`
-	PlacedDate          string    `json:"placedDate"`
-	MatchedDate         string    `json:"matchedDate"`
-	AveragePriceMatched float64   `json:"averagePriceMatched"`
-	SizeMatched         float64   `json:"sizeMatched"`
-	SizeRemaining       float64   `json:"sizeRemaining"`
-	SizeLapsed          float64   `json:"sizeLapsed"`
-	SizeCancelled       float64   `json:"sizeCancelled"`
-	SizeVoided          float64   `json:"sizeVoided"`
-	RegulatorAuthCode   string    `json:"regulatorAuthCode"`
-	RegulatorCode       string    `json:"regulatorCode"`
-}
+	BetID               string          `json:"betId"`
+	MarketID            string          `json:"marketId"`
+	SelectionID         int64           `json:"selectionId"`
+	Handicap            float64         `json:"handicap"`
+	PriceSize           PriceSize       `json:"priceSize"`
+	BSPLiability        float64         `json:"bspLiability"`
+	Side                Side            `json:"side"`
+	Status              OrderStatus     `json:"status"`
+	PersistenceType     PersistenceType `json:"persistenceType"`
+	OrderType           OrderType       `json:"orderType"`
+	PlacedDate          time.Time       `json:"placedDate"`
+	MatchedDate         *time.Time      `json:"matchedDate"`
+	AveragePriceMatched float64         `json:"averagePriceMatched"`
+	SizeMatched         float64         `json:"sizeMatched"`
+	SizeRemaining       float64         `json:"sizeRemaining"`
+	SizeLapsed          float64         `json:"sizeLapsed"`
+	SizeCancelled       float64         `json:"sizeCancelled"`
+	SizeVoided          float64         `json:"sizeVoided"`
+	RegulatorAuthCode   string          `json:"regulatorAuthCode"`
+	RegulatorCode       string          `json:"regulatorCode"`
+	CustomerOrderRef    string          `json:"customerOrderRef"`
+	CustomerStrategyRef string          `json:"customerStrategyRef"`
+}
+
+type OrderProjection string
+
+const (
+	OrderProjectionAll               OrderProjection = "ALL"
+	OrderProjectionExecutable        OrderProjection = "EXECUTABLE"
+	OrderProjectionExecutionComplete OrderProjection = "EXECUTION_COMPLETE"
+)
+
+type OrderBy string
+
+const (
+	OrderByBet         OrderBy = "BY_BET"
+	OrderByMarket      OrderBy = "BY_MARKET"
+	OrderByMatchTime   OrderBy = "BY_MATCH_TIME"
+	OrderByPlaceTime   OrderBy = "BY_PLACE_TIME"
+	OrderBySettledTime OrderBy = "BY_SETTLED_TIME"
+	OrderByVoidTime    OrderBy = "BY_VOID_TIME"
+)
+
+type SortDir string
+
+const (
+	SortDirEarliestToLatest SortDir = "EARLIEST_TO_LATEST"
+	SortDirLatestToEarliest SortDir = "LATEST_TO_EARLIEST"
+)
 
 type CurrentOrderSummaryReport struct {
 	CurrentOrders []CurrentOrderSumary `json:"currentOrders"`
 	MoreAvailable bool                 `json:"moreAvailable"`
 }
 
+type ItemDescription struct {
+	EventTypeDesc   string    `json:"eventTypeDesc"`
+	EventDesc       string    `json:"eventDesc"`
+	MarketDesc      string    `json:"marketDesc"`
+	MarketType      string    `json:"marketType"`
+	MarketStartTime time.Time `json:"marketStartTime"`
+	RunnerDesc      string    `json:"runnerDesc"`
+	NumberOfWinners int64     `json:"numberOfWinners"`
+	EachWayDivisor  float64   `json:"eachWayDivisor"`
+}
+
 type ClearedOrderSummary struct {
-	BetID           string    `json:"betId"`
-	MarketID        string    `json:"marketId"`
-	SelectionID     int64     `json:"selectionId"`
-	Handicap        float64   `json:"handicap"`
-	EventID         int64     `json:"eventId"`
-	EventTypeID     int64     `json:"eventTypeId"`
-	PlacedDate      time.Time `json:"placedDate"`
-	PersistenceType string    `json:"persistenceType"`
-	OrderType       string    `json:"orderType"`
-	Side            string    `json:"side"`
-	ItemDescription string    `json:"itemDescription"` //
-	PriceRequested  float64   `json:"priceRequested"`
-	SettledDate     time.Time `json:"settledDate"`
-	BetCount        int64     `json:"betCount"`
-	Commission      float64   `json:"commission"`
-	PriceMatched    float64   `json:"priceMatched"`
-	PriceReduced    float64   `json:"priceReduced"`
-	SizeSettled     float64   `json:"sizeSettled"`
-	Profit          float64   `json:"profit"`
-	SizeCancelled   float64   `json:"sizeCancelled"`
+	BetID               string           `json:"betId"`
+	MarketID            string           `json:"marketId"`
+	SelectionID         int64            `json:"selectionId"`
+	Handicap            float64          `json:"handicap"`
+	EventID             string           `json:"eventId"`
+	EventTypeID         string           `json:"eventTypeId"`
+	PlacedDate          time.Time        `json:"placedDate"`
+	PersistenceType     PersistenceType  `json:"persistenceType"`
+	OrderType           OrderType        `json:"orderType"`
+	Side                Side             `json:"side"`
+	ItemDescription     *ItemDescription `json:"itemDescription"`
+	BetOutcome          string           `json:"betOutcome"`
+	PriceRequested      float64          `json:"priceRequested"`
+	SettledDate         time.Time        `json:"settledDate"`
+	LastMatchedDate     time.Time        `json:"lastMatchedDate"`
+	BetCount            int64            `json:"betCount"`
+	Commission          float64          `json:"commission"`
+	PriceMatched        float64          `json:"priceMatched"`
+	PriceReduced        bool             `json:"priceReduced"`
+	SizeSettled         float64          `json:"sizeSettled"`
+	Profit              float64          `json:"profit"`
+	SizeCancelled       float64          `json:"sizeCancelled"`
+	CustomerOrderRef    string           `json:"customerOrderRef"`
+	CustomerStrategyRef string           `json:"customerStrategyRef"`
+}
+
+type BetStatus string
+
+const (
+	BetStatusSettled   BetStatus = "SETTLED"
+	BetStatusVoided    BetStatus = "VOIDED"
+	BetStatusLapsed    BetStatus = "LAPSED"
+	BetStatusCancelled BetStatus = "CANCELLED"
+)
+
+type RunnerID struct {
+	MarketID    string  `json:"marketId"`
+	SelectionID int64   `json:"selectionId"`
+	Handicap    float64 `json:"handicap,omitempty"`
 }
 
+type GroupBy string
+
+const (
+	GroupByEventType GroupBy = "EVENT_TYPE"
+	GroupByEvent     GroupBy = "EVENT"
+	GroupByMarket    GroupBy = "MARKET"
+	GroupBySide      GroupBy = "SIDE"
+	GroupByBet       GroupBy = "BET"
+)
+
 type ClearedOrderSummaryReport struct {
 	ClearedOrers  []ClearedOrderSummary `json:"clearedOrders"`
 	MoreAvailable bool                  `json:"moreAvailable"`
+	// GroupBy of the request, the rows are aggregates unless blank or BET
+	GroupBy GroupBy `json:"-"`
+}
+
+// ClearedOrderTotals are the figures the exchange rolls up for every grouping
+type ClearedOrderTotals struct {
+	BetCount        int64
+	Profit          float64
+	Commission      float64
+	SettledDate     time.Time
+	LastMatchedDate time.Time
+	ItemDescription *ItemDescription
+}
+
+type ClearedEventTypeTotals struct {
+	EventTypeID string
+	ClearedOrderTotals
+}
+
+type ClearedEventTotals struct {
+	EventTypeID string
+	EventID     string
+	ClearedOrderTotals
+}
+
+type ClearedMarketTotals struct {
+	EventTypeID string
+	EventID     string
+	MarketID    string
+	ClearedOrderTotals
+}
+
+type ClearedSideTotals struct {
+	EventTypeID  string
+	EventID      string
+	MarketID     string
+	SelectionID  int64
+	Handicap     float64
+	Side         Side
+	PriceMatched float64
+	SizeSettled  float64
+	ClearedOrderTotals
+}
+
+type OrderType string
+
+const (
+	OrderTypeLimit         OrderType = "LIMIT"
+	OrderTypeLimitOnClose  OrderType = "LIMIT_ON_CLOSE"
+	OrderTypeMarketOnClose OrderType = "MARKET_ON_CLOSE"
+)
+
+func (orderType OrderType) IsValid() bool {
+	switch orderType {
+	case OrderTypeLimit, OrderTypeLimitOnClose, OrderTypeMarketOnClose:
+		return true
+	}
+
+	return false
+}
+
+type Side string
+
+const (
+	SideBack Side = "BACK"
+	SideLay  Side = "LAY"
+)
+
+func (side Side) IsValid() bool {
+	return side == SideBack || side == SideLay
+}
+
+type PersistenceType string
+
+const (
+	PersistenceTypeLapse         PersistenceType = "LAPSE"
+	PersistenceTypePersist       PersistenceType = "PERSIST"
+	PersistenceTypeMarketOnClose PersistenceType = "MARKET_ON_CLOSE"
+)
+
+func (persistenceType PersistenceType) IsValid() bool {
+	switch persistenceType {
+	case PersistenceTypeLapse, PersistenceTypePersist, PersistenceTypeMarketOnClose:
+		return true
+	}
+
+	return false
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusPending           OrderStatus = "PENDING"
+	OrderStatusExecutionComplete OrderStatus = "EXECUTION_COMPLETE"
+	OrderStatusExecutable        OrderStatus = "EXECUTABLE"
+	OrderStatusExpired           OrderStatus = "EXPIRED"
+)
+
+func (status OrderStatus) IsValid() bool {
+	switch status {
+	case OrderStatusPending, OrderStatusExecutionComplete, OrderStatusExecutable, OrderStatusExpired:
+		return true
+	}
+
+	return false
+}
+
+type LimitOrder struct {
+	Size            float64         `json:"size,omitempty"`
+	Price           float64         `json:"price"`
+	PersistenceType PersistenceType `json:"persistenceType,omitempty"`
+	TimeInForce     string          `json:"timeInForce,omitempty"`
+	MinFillSize     float64         `json:"minFillSize,omitempty"`
+	BetTargetType   string          `json:"betTargetType,omitempty"`
+	BetTargetSize   float64         `json:"betTargetSize,omitempty"`
+}
+
+type LimitOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+	Price     float64 `json:"price"`
+}
+
+type MarketOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+}
+
+type PlaceInstruction struct {
+	OrderType          OrderType           `json:"orderType"`
+	SelectionID        int64               `json:"selectionId"`
+	Handicap           float64             `json:"handicap,omitempty"`
+	Side               Side                `json:"side"`
+	LimitOrder         *LimitOrder         `json:"limitOrder,omitempty"`
+	LimitOnCloseOrder  *LimitOnCloseOrder  `json:"limitOnCloseOrder,omitempty"`
+	MarketOnCloseOrder *MarketOnCloseOrder `json:"marketOnCloseOrder,omitempty"`
+	CustomerOrderRef   string              `json:"customerOrderRef,omitempty"`
+}
+
+type MarketVersion struct {
+	Version int64 `json:"version"`
+}
+
+type PlaceInstructionReport struct {
+	Status              string           `json:"status"`
+	ErrorCode           string           `json:"errorCode"`
+	OrderStatus         OrderStatus      `json:"orderStatus"`
+	Instruction         PlaceInstruction `json:"instruction"`
+	BetID               string           `json:"betId"`
+	PlacedDate          time.Time        `json:"placedDate"`
+	AveragePriceMatched float64          `json:"averagePriceMatched"`
+	SizeMatched         float64          `json:"sizeMatched"`
+}
+
+type PlaceExecutionReport struct {
+	CustomerRef        string                   `json:"customerRef"`
+	Status             string                   `json:"status"`
+	ErrorCode          string                   `json:"errorCode"`
+	MarketID           string                   `json:"marketId"`
+	InstructionReports []PlaceInstructionReport `json:"instructionReports"`
+}
+
+type CancelInstruction struct {
+	BetID         string  `json:"betId"`
+	SizeReduction float64 `json:"sizeReduction,omitempty"`
+}
+
+type CancelInstructionReport struct {
+	Status        string            `json:"status"`
+	ErrorCode     string            `json:"errorCode"`
+	Instruction   CancelInstruction `json:"instruction"`
+	SizeCancelled float64           `json:"sizeCancelled"`
+	CancelledDate time.Time         `json:"cancelledDate"`
+}
+
+type CancelExecutionReport struct {
+	CustomerRef        string                    `json:"customerRef"`
+	Status             string                    `json:"status"`
+	ErrorCode          string                    `json:"errorCode"`
+	MarketID           string                    `json:"marketId"`
+	InstructionReports []CancelInstructionReport `json:"instructionReports"`
+}
+
+type ReplaceInstruction struct {
+	BetID    string  `json:"betId"`
+	NewPrice float64 `json:"newPrice"`
+}
+
+type ReplaceInstructionReport struct {
+	Status                  string                   `json:"status"`
+	ErrorCode               string                   `json:"errorCode"`
+	CancelInstructionReport *CancelInstructionReport `json:"cancelInstructionReport"`
+	PlaceInstructionReport  *PlaceInstructionReport  `json:"placeInstructionReport"`
+}
+
+type ReplaceExecutionReport struct {
+	CustomerRef        string                     `json:"customerRef"`
+	Status             string                     `json:"status"`
+	ErrorCode          string                     `json:"errorCode"`
+	MarketID           string                     `json:"marketId"`
+	InstructionReports []ReplaceInstructionReport `json:"instructionReports"`
+}
+
+// BetIDs maps replaced bet ids to the ids of the bets placed in their stead
+func (report ReplaceExecutionReport) BetIDs() map[string]string {
+	var betIDs = map[string]string{}
+
+	for _, instructionReport := range report.InstructionReports {
+		if instructionReport.CancelInstructionReport == nil || instructionReport.PlaceInstructionReport == nil {
+			continue
+		}
+
+		if instructionReport.PlaceInstructionReport.BetID == "" {
+			continue
+		}
+
+		betIDs[instructionReport.CancelInstructionReport.Instruction.BetID] = instructionReport.PlaceInstructionReport.BetID
+	}
+
+	return betIDs
+}
+
+type UpdateInstruction struct {
+	BetID              string          `json:"betId"`
+	NewPersistenceType PersistenceType `json:"newPersistenceType"`
+}
+
+type UpdateInstructionReport struct {
+	Status      string            `json:"status"`
+	ErrorCode   string            `json:"errorCode"`
+	Instruction UpdateInstruction `json:"instruction"`
+}
+
+type UpdateExecutionReport struct {
+	CustomerRef        string                    `json:"customerRef"`
+	Status             string                    `json:"status"`
+	ErrorCode          string                    `json:"errorCode"`
+	MarketID           string                    `json:"marketId"`
+	InstructionReports []UpdateInstructionReport `json:"instructionReports"`
+}
+
+type RunnerProfitAndLoss struct {
+	SelectionID int64   `json:"selectionId"`
+	IfWin       float64 `json:"ifWin"`
+	IfLose      float64 `json:"ifLose"`
+	IfPlace     float64 `json:"ifPlace"`
+}
+
+type MarketProfitAndLoss struct {
+	MarketID          string                `json:"marketId"`
+	CommissionApplied float64               `json:"commissionApplied"`
+	ProfitAndLosses   []RunnerProfitAndLoss `json:"profitAndLosses"`
 }
@@ -0,0 +1,183 @@
+package betfair
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPriceLadderTicks(t *testing.T) {
+	var cases = []struct {
+		price Price
+		ticks int
+		up    Price
+		down  Price
+	}{
+		{1.01, 1, 1.02, 1.01},
+		{1.99, 2, 2.02, 1.97},
+		{2.98, 2, 3.05, 2.94},
+		{4, 1, 4.1, 3.95},
+		{19.5, 3, 22, 18},
+		{950, 10, 1000, 850},
+		{1000, 1, 1000, 990},
+	}
+
+	for _, c := range cases {
+		if up := c.price.TickUp(c.ticks); up != c.up {
+			t.Errorf("%v up %d ticks should be %v, got %v", c.price, c.ticks, c.up, up)
+		}
+
+		if down := c.price.TickDown(c.ticks); down != c.down {
+			t.Errorf("%v down %d ticks should be %v, got %v", c.price, c.ticks, c.down, down)
+		}
+	}
+
+	if ticks := ClassicPriceLadder.Ticks(1.01, 2); ticks != 99 {
+		t.Errorf("Expected 99 ticks between 1.01 and 2, got %d", ticks)
+	}
+
+	if ticks := ClassicPriceLadder.Ticks(3.05, 2.98); ticks != -2 {
+		t.Errorf("Expected -2 ticks between 3.05 and 2.98, got %d", ticks)
+	}
+
+	if ticks := ClassicPriceLadder.Ticks(1.01, 1000); ticks != 349 {
+		t.Errorf("Expected 349 ticks on the classic ladder, got %d", ticks)
+	}
+
+	if ticks := FinestPriceLadder.Ticks(1.01, 2); ticks != 99 {
+		t.Errorf("Expected 99 ticks between 1.01 and 2, got %d", ticks)
+	}
+}
+
+func TestPriceLadderRound(t *testing.T) {
+	var cases = []struct {
+		price     Price
+		direction RoundDirection
+		rounded   Price
+	}{
+		{3.07, RoundUp, 3.1},
+		{3.07, RoundDown, 3.05},
+		{3.07, RoundNearest, 3.05},
+		{3.08, RoundNearest, 3.1},
+		{3.05, RoundUp, 3.05},
+		{1, RoundDown, 1.01},
+		{2000, RoundUp, 1000},
+		{115, RoundNearest, 110},
+		{1.428571, RoundDown, 1.42},
+		{1.428571, RoundUp, 1.43},
+		{1.428571, RoundNearest, 1.43},
+		{1.014, RoundUp, 1.02},
+		{1.014, RoundDown, 1.01},
+		{1.014, RoundNearest, 1.01},
+		{3.051, RoundUp, 3.1},
+		{3.099, RoundDown, 3.05},
+	}
+
+	for _, c := range cases {
+		if rounded := c.price.Round(c.direction); rounded != c.rounded {
+			t.Errorf("%v rounded %v should be %v, got %v", c.price, c.direction, c.rounded, rounded)
+		}
+	}
+
+	if rounded := FinestPriceLadder.Round(3.07, RoundUp); rounded != 3.07 {
+		t.Errorf("3.07 is a FINEST tick, got %v", rounded)
+	}
+
+	if up := ClassicPriceLadder.TickUp(1.428571, 1); up != 1.44 {
+		t.Errorf("Expected 1.44 one tick above 1.428571, got %v", up)
+	}
+
+	if down := ClassicPriceLadder.TickDown(1.428571, 1); down != 1.41 {
+		t.Errorf("Expected 1.41 one tick below 1.428571, got %v", down)
+	}
+}
+
+func TestLineRangePriceLadder(t *testing.T) {
+	description := MarketDescription{
+		PriceLadderDescription: &PriceLadderDescription{Type: PriceLadderLineRange},
+		LineRangeInfo:          &MarketLineRangeInfo{MinUnitValue: -10.5, MaxUnitValue: 10.5, Interval: 1},
+	}
+
+	ladder := description.PriceLadder()
+
+	if !ladder.IsValid(-10.5) || !ladder.IsValid(0.5) || ladder.IsValid(1) || ladder.IsValid(11.5) {
+		t.Error("Unexpected line range validity")
+	}
+
+	if up := ladder.TickUp(0.5, 3); up != 3.5 {
+		t.Errorf("Expected 3.5, got %v", up)
+	}
+
+	if down := ladder.TickDown(-9.5, 3); down != -10.5 {
+		t.Errorf("Expected -10.5, got %v", down)
+	}
+
+	if ticks := ladder.Ticks(-10.5, 10.5); ticks != 21 {
+		t.Errorf("Expected 21 ticks, got %d", ticks)
+	}
+
+	if (MarketDescription{}).PriceLadder().Type != PriceLadderClassic {
+		t.Error("Markets without a ladder description should use the CLASSIC ladder")
+	}
+}
+
+func TestEnumJSON(t *testing.T) {
+	var order CurrentOrderSumary
+	body := `{"betId":"1","side":"LAY","status":"EXECUTABLE","persistenceType":"PERSIST","orderType":"LIMIT","placedDate":"2016-05-01T12:30:15.000Z"}`
+
+	if err := json.Unmarshal([]byte(body), &order); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if order.Side != SideLay || order.Status != OrderStatusExecutable || order.PersistenceType != PersistenceTypePersist || order.OrderType != OrderTypeLimit {
+		t.Errorf("Unexpected order %+v", order)
+	}
+
+	if !order.PlacedDate.Equal(time.Date(2016, 5, 1, 12, 30, 15, 0, time.UTC)) || order.MatchedDate != nil {
+		t.Errorf("Unexpected dates %v, %v", order.PlacedDate, order.MatchedDate)
+	}
+
+	encoded, err := json.Marshal(order)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var decoded CurrentOrderSumary
+
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Side != order.Side || !decoded.PlacedDate.Equal(order.PlacedDate) {
+		t.Errorf("Round trip failed %v, %+v", err, decoded)
+	}
+
+	var book MarketBook
+
+	if err := json.Unmarshal([]byte(`{"status":"OPEN","runners":[{"status":"REMOVED","removalDate":"2016-05-01T11:00:00.000Z"}]}`), &book); err != nil || book.Status != MarketStatusOpen || book.Runners[0].Status != RunnerStatusRemoved || book.Runners[0].RemovalDate == nil {
+		t.Errorf("Unexpected book %v, %+v", err, book)
+	}
+}
+
+func TestEnumUnknownValues(t *testing.T) {
+	var books []MarketBook
+	body := `[{"marketId":"1.1","status":"OPEN","runners":[{"selectionId":101,"status":"NEW_STATUS"}]},
+		{"marketId":"1.2","status":"OPEN","runners":[{"selectionId":201,"status":"ACTIVE"},{"selectionId":202,"status":"ACTIVE"}]}]`
+
+	if err := json.Unmarshal([]byte(body), &books); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(books) != 2 || len(books[0].Runners) != 1 || len(books[1].Runners) != 2 {
+		t.Errorf("Unexpected books %+v", books)
+		return
+	}
+
+	if status := books[0].Runners[0].Status; status != "NEW_STATUS" || status.IsValid() {
+		t.Errorf("Unexpected status %s", status)
+	}
+
+	if !books[1].Runners[0].Status.IsValid() {
+		t.Errorf("Expected a known status")
+	}
+}
@@ -0,0 +1,144 @@
+package betfair
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Instructions per hour the exchange allows before charging
+var HourlyTransactionLimit = 5000
+
+// TransactionLimitError is returned when instructions would exceed the soft cap
+type TransactionLimitError struct {
+	Requested int
+	Remaining int
+}
+
+func (err *TransactionLimitError) Error() string {
+	return fmt.Sprintf("Transaction limit reached: %d instructions requested, %d remaining", err.Requested, err.Remaining)
+}
+
+type transactionEvent struct {
+	at    time.Time
+	count int
+}
+
+// transactionCounter keeps instructions counted towards the hourly limit
+// over a sliding window of one hour
+type transactionCounter struct {
+	m       sync.Mutex
+	softCap int
+	events  []transactionEvent
+}
+
+func (counter *transactionCounter) prune(now time.Time) int {
+	var total int
+	var kept = counter.events[:0]
+
+	for _, event := range counter.events {
+		if now.Sub(event.at) < time.Hour {
+			kept = append(kept, event)
+			total += event.count
+		}
+	}
+
+	counter.events = kept
+	return total
+}
+
+func (counter *transactionCounter) capacity() int {
+	if counter.softCap > 0 {
+		return counter.softCap
+	}
+
+	return HourlyTransactionLimit
+}
+
+func (counter *transactionCounter) count() int {
+	counter.m.Lock()
+	defer counter.m.Unlock()
+
+	return counter.prune(time.Now())
+}
+
+func (counter *transactionCounter) remaining() int {
+	counter.m.Lock()
+	defer counter.m.Unlock()
+
+	remaining := counter.capacity() - counter.prune(time.Now())
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+func (counter *transactionCounter) add(count int) {
+	if count <= 0 {
+		return
+	}
+
+	counter.m.Lock()
+	defer counter.m.Unlock()
+
+	counter.events = append(counter.events, transactionEvent{at: time.Now(), count: count})
+}
+
+// reserve counts instructions about to be sent, refusing them when the soft
+// cap is set and would be exceeded
+func (counter *transactionCounter) reserve(count int) error {
+	counter.m.Lock()
+	defer counter.m.Unlock()
+
+	now := time.Now()
+	total := counter.prune(now)
+
+	if counter.softCap > 0 && total+count > counter.softCap {
+		return &TransactionLimitError{Requested: count, Remaining: counter.softCap - total}
+	}
+
+	counter.events = append(counter.events, transactionEvent{at: now, count: count})
+	return nil
+}
+
+// TransactionCount returns the instructions counted during the last hour
+func (api *API) TransactionCount() int {
+	return api.transactions.count()
+}
+
+// TransactionsRemaining returns the instructions left before the soft cap, or
+// before HourlyTransactionLimit when no soft cap is set
+func (api *API) TransactionsRemaining() int {
+	return api.transactions.remaining()
+}
+
+// SetTransactionSoftCap refuses place and replace instructions above limit
+// per hour, zero disables the cap
+func (api *API) SetTransactionSoftCap(limit int) {
+	api.transactions.m.Lock()
+	defer api.transactions.m.Unlock()
+
+	api.transactions.softCap = limit
+}
+
+func countFailedCancelInstructions(report CancelExecutionReport) (failed int) {
+	for _, instructionReport := range report.InstructionReports {
+		if instructionReport.Status == "FAILURE" {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func countFailedUpdateInstructions(report UpdateExecutionReport) (failed int) {
+	for _, instructionReport := range report.InstructionReports {
+		if instructionReport.Status == "FAILURE" {
+			failed++
+		}
+	}
+
+	return failed
+}
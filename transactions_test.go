@@ -0,0 +1,77 @@
+package betfair
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransactionSoftCap(t *testing.T) {
+	var calls int
+
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		calls++
+		return PlaceExecutionReport{Status: "SUCCESS"}
+	})
+
+	api.SetTransactionSoftCap(3)
+
+	instructions := []PlaceInstruction{getTestLimitInstruction(2, 2), getTestLimitInstruction(2, 2)}
+
+	if _, err := api.PlaceOrders("1.114363660", instructions, Options{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if api.TransactionCount() != 2 || api.TransactionsRemaining() != 1 {
+		t.Errorf("Unexpected count %d and remaining %d", api.TransactionCount(), api.TransactionsRemaining())
+	}
+
+	_, err := api.PlaceOrders("1.114363660", instructions, Options{})
+
+	if err, ok := err.(*TransactionLimitError); !ok || err.Remaining != 1 || err.Requested != 2 {
+		t.Errorf("Expected transaction limit error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Refused instructions should not be sent, got %d calls", calls)
+	}
+
+	api.SetTransactionSoftCap(0)
+
+	if api.TransactionsRemaining() != HourlyTransactionLimit-2 {
+		t.Errorf("Unexpected remaining %d", api.TransactionsRemaining())
+	}
+}
+
+func TestTransactionFailedCancels(t *testing.T) {
+	api := getMockAPI(t, func(call mockCall) interface{} {
+		return CancelExecutionReport{
+			Status:             "PROCESSED_WITH_ERRORS",
+			InstructionReports: []CancelInstructionReport{{Status: "SUCCESS"}, {Status: "FAILURE"}},
+		}
+	})
+
+	instructions := []CancelInstruction{{BetID: "31242604945"}, {BetID: "31242604946"}}
+
+	if _, err := api.CancelOrders("1.114363660", instructions, Options{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if api.TransactionCount() != 1 {
+		t.Errorf("Only failed cancels should be counted, got %d", api.TransactionCount())
+	}
+}
+
+func TestTransactionWindow(t *testing.T) {
+	var counter transactionCounter
+
+	counter.events = []transactionEvent{
+		{at: time.Now().Add(-time.Hour - time.Minute), count: 10},
+		{at: time.Now().Add(-time.Minute), count: 3},
+	}
+
+	if count := counter.count(); count != 3 {
+		t.Errorf("Expected instructions older than an hour to expire, got %d", count)
+	}
+}